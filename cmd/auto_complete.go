@@ -5,8 +5,9 @@ import (
 )
 
 var autoCompleteCmd = &cobra.Command{
-	Use:   "auto-complete",
-	Short: "Generate auto-completion for your shell",
+	Use:         "auto-complete",
+	Short:       "Generate auto-completion for your shell",
+	Annotations: map[string]string{skipSetupAnnotation: "true"},
 }
 
 func init() {
@@ -17,9 +17,4 @@ func init() {
 	if err := viper.BindPFlag("github-access-token", codeCmd.Flags().Lookup("github-access-token")); err != nil {
 		panic(err)
 	}
-
-	codeCmd.Flags().String("exclude", "", "The pattern to exclude")
-	if err := viper.BindPFlag("exclude", codeCmd.Flags().Lookup("exclude")); err != nil {
-		panic(err)
-	}
 }
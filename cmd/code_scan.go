@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var codeScanCmd = &cobra.Command{
+	Use:         "scan",
+	Short:       "Scan the code directory and refresh the project index",
+	Annotations: map[string]string{skipScanAnnotation: "true"},
+	PreRunE:     requireCodePath,
+	RunE:        codeScanRun,
+}
+
+func init() {
+	codeCmd.AddCommand(codeScanCmd)
+
+	codeScanCmd.Flags().Bool("rebuild", false, "Ignore the project index and scan the entire code directory")
+}
+
+func codeScanRun(cmd *cobra.Command, args []string) error {
+	rebuild, err := cmd.Flags().GetBool("rebuild")
+	if err != nil {
+		return errors.Wrap(err, "error getting the value of the --rebuild flag")
+	}
+
+	if rebuild {
+		if err := warnScanErrors(code.Rebuild()); err != nil {
+			return errors.Wrap(err, "error rebuilding the project index")
+		}
+	} else if err := warnScanErrors(code.Scan()); err != nil {
+		return errors.Wrap(err, "error scanning the code")
+	}
+
+	fmt.Printf("Found %d projects in %s\n", len(code.Projects()), code.RepositoriesDir())
+
+	return nil
+}
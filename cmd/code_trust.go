@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+
+	"github.com/kalbasit/swm/manifest"
+	vcsPkg "github.com/kalbasit/swm/vcs"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var codeTrustCmd = &cobra.Command{
+	Use:   "trust [dir]",
+	Short: "Allow the manifest of a repository or a story to be used",
+	Long: `Allow the .swm.yaml manifest of a repository or a story to be used, the
+current directory by default. The setup commands, the environment and the
+layout of a manifest are ignored until it is trusted, and it has to be
+trusted again once it changes. The trust is given to the repository, it
+covers the stories of the repository as long as their manifest is the same.
+The setup of a story is resumed the next time the project is opened in the
+story.`,
+	Args:        cobra.MaximumNArgs(1),
+	Annotations: map[string]string{skipSetupAnnotation: "true"},
+	RunE:        codeTrustRun,
+}
+
+func init() {
+	codeCmd.AddCommand(codeTrustCmd)
+}
+
+func codeTrustRun(cmd *cobra.Command, args []string) error {
+	dir := "."
+	if len(args) == 1 {
+		dir = args[0]
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return errors.Wrap(err, "error getting the absolute path of the directory")
+	}
+
+	m, err := manifest.Load(dir)
+	if err != nil {
+		return err
+	}
+	if trusted, err := m.Trusted(); err != nil {
+		return err
+	} else if trusted {
+		fmt.Printf("The manifest %s is already trusted\n", path.Join(dir, manifest.FileName))
+		return nil
+	}
+
+	// the manifest of a story is trusted by its repository
+	v, err := vcsPkg.Detect(dir)
+	if err != nil {
+		return errors.Wrapf(err, "%s is not a repository", dir)
+	}
+	if m.Repository, err = v.RepositoryRoot(dir); err != nil {
+		return errors.Wrap(err, "error finding the repository")
+	}
+
+	if err := m.Trust(); err != nil {
+		return errors.Wrap(err, "error trusting the manifest")
+	}
+
+	fmt.Printf("Trusted the manifest %s for the repository %s\n", path.Join(dir, manifest.FileName), m.Repository)
+	for _, command := range m.Setup {
+		fmt.Printf("  setup: %s\n", command)
+	}
+	for _, e := range m.Env {
+		fmt.Printf("  env: %s\n", e)
+	}
+
+	return nil
+}
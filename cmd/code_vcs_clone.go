@@ -1,10 +1,15 @@
 package cmd
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"golang.org/x/tools/go/vcs"
+
+	vcsPkg "github.com/kalbasit/swm/vcs"
 )
 
 var errNotBoth = errors.New("you cannot specify both --go-import-path and --clone-url")
@@ -22,6 +27,7 @@ func init() {
 
 	codeVcsCloneCmd.Flags().String("go-import-path", "", "Clone a repository by its Go import path")
 	codeVcsCloneCmd.Flags().String("clone-url", "", "Clone a repository by its clone URL")
+	codeVcsCloneCmd.Flags().String("vcs", "", fmt.Sprintf("The version control system to clone with, one of %s. Defaults to %s, or to the one of the Go import path", strings.Join(vcsPkg.Names(), ", "), vcsPkg.DefaultName))
 }
 
 func codeVcsCloneCmdRun(cmd *cobra.Command, args []string) error {
@@ -35,6 +41,11 @@ func codeVcsCloneCmdRun(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	vn, err := cmd.Flags().GetString("vcs")
+	if err != nil {
+		return err
+	}
+
 	if gip == "" && cu == "" {
 		// re-show the usage for this error
 		cmd.SilenceUsage = false
@@ -56,7 +67,10 @@ func codeVcsCloneCmdRun(cmd *cobra.Command, args []string) error {
 		}
 
 		cu = rr.Repo
+		if vn == "" {
+			vn = rr.VCS.Cmd
+		}
 	}
 
-	return code.Clone(cu)
+	return code.Clone(cu, vn)
 }
@@ -0,0 +1,340 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/kalbasit/swm/ifaces"
+	"github.com/kalbasit/swm/story"
+	"github.com/kalbasit/swm/tools"
+	"github.com/olekukonko/tablewriter"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	codePkg "github.com/kalbasit/swm/code"
+)
+
+const (
+	doctorOK      = "ok"
+	doctorWarning = "warning"
+	doctorError   = "error"
+)
+
+// toolUsages describes what each tool is needed for
+var toolUsages = map[string]string{
+	tools.Git:  "Git repositories",
+	tools.Hg:   "Mercurial repositories",
+	tools.Jj:   "Jujutsu repositories",
+	tools.Tmux: "swm tmux",
+	tools.Fzf:  "the fzf selector",
+	tools.Ps:   "the processes of the tmux panes on systems without /proc",
+
+	tools.Zellij: "the zellij multiplexer",
+
+	tools.Sk:    "the skim selector",
+	tools.Rofi:  "the rofi selector",
+	tools.Dmenu: "the dmenu selector",
+}
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose the tools, the configuration and the code path",
+	Long: `Diagnose the tools, the configuration and the code path.
+
+It reports the version of the external tools, the problems of the config file,
+the problems of the layout of the code path and the stale worktrees of the
+projects. It exits with an error if any problem must be fixed.`,
+	Annotations: map[string]string{skipSetupAnnotation: "true"},
+	RunE:        doctorRun,
+}
+
+// doctorCheck is the result of a single check.
+type doctorCheck struct {
+	name   string
+	status string
+	detail string
+}
+
+// doctorReport accumulates the results of the checks.
+type doctorReport struct {
+	checks []doctorCheck
+}
+
+func (r *doctorReport) add(name, status, detail string) {
+	r.checks = append(r.checks, doctorCheck{name: name, status: status, detail: detail})
+}
+
+func (r *doctorReport) count(status string) int {
+	var n int
+	for _, c := range r.checks {
+		if c.status == status {
+			n++
+		}
+	}
+
+	return n
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+}
+
+func doctorRun(cmd *cobra.Command, args []string) error {
+	report := &doctorReport{}
+
+	doctorConfig(report)
+	doctorTools(report)
+	if c := doctorLayout(report); c != nil {
+		doctorWorktrees(report, c)
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetAutoWrapText(false)
+	table.SetAutoFormatHeaders(false)
+	table.SetHeader([]string{"Check", "Status", "Details"})
+	for _, c := range report.checks {
+		table.Append([]string{c.name, c.status, c.detail})
+	}
+	table.Render()
+
+	if n := report.count(doctorError); n > 0 {
+		return errors.Errorf("found %d problems and %d warnings", n, report.count(doctorWarning))
+	}
+	fmt.Printf("No problems found, %d warnings\n", report.count(doctorWarning))
+
+	return nil
+}
+
+// doctorConfig validates the config file and the settings.
+func doctorConfig(report *doctorReport) {
+	switch {
+	case errConfig != nil:
+		report.add("config file", doctorError, errConfig.Error())
+	case viper.ConfigFileUsed() != "" && fileExists(viper.ConfigFileUsed()):
+		report.add("config file", doctorOK, viper.ConfigFileUsed())
+	default:
+		report.add("config file", doctorOK, "not found, using the defaults")
+	}
+
+	for name, p := range viper.GetStringMapString("tools") {
+		if err := tools.SetPath(name, p); err != nil {
+			report.add("config tools."+name, doctorError, err.Error())
+		}
+	}
+
+	if es := viper.GetString("exclude"); es != "" {
+		if _, err := regexp.Compile(es); err != nil {
+			report.add("config exclude", doctorError, err.Error())
+		}
+	}
+
+	if _, err := layoutConfig(); err != nil {
+		report.add("config layouts", doctorError, err.Error())
+	}
+}
+
+// doctorTools reports the path and the version of each tool.
+func doctorTools(report *doctorReport) {
+	for _, name := range tools.Names() {
+		p, err := tools.Path(name)
+		if err != nil {
+			status := doctorWarning
+			if name == tools.Git {
+				status = doctorError
+			}
+			report.add("tool "+name, status, fmt.Sprintf("%s not found, it's required by %s", tools.Configured(name), toolUsages[name]))
+			continue
+		}
+
+		version, err := tools.Version(name)
+		if err != nil {
+			report.add("tool "+name, doctorWarning, fmt.Sprintf("%s: %s", p, err))
+			continue
+		}
+		report.add("tool "+name, doctorOK, fmt.Sprintf("%s (%s)", p, version))
+	}
+}
+
+// doctorLayout validates the layout of the code path, it returns the code if
+// it can be scanned.
+func doctorLayout(report *doctorReport) ifaces.Code {
+	cp := viper.GetString("code-path")
+	if cp == "" {
+		report.add("code path", doctorError, errCodePathIsRequired.Error())
+		return nil
+	}
+	if !dirExists(cp) {
+		report.add("code path", doctorError, fmt.Sprintf("%s does not exist", cp))
+		return nil
+	}
+	report.add("code path", doctorOK, cp)
+
+	var ignorePattern *regexp.Regexp
+	if es := viper.GetString("exclude"); es != "" {
+		// an invalid pattern was already reported
+		ignorePattern, _ = regexp.Compile(es)
+	}
+	c := codePkg.New(cp, ignorePattern,
+		codePkg.WithParallelism(viper.GetInt("scan-parallelism")),
+		codePkg.WithMaxDepth(viper.GetInt("scan-max-depth")),
+		codePkg.WithSkipDirs(viper.GetStringSlice("scan-skip-dirs")...),
+		codePkg.WithRepositoriesDirname(viper.GetString("repositories-dirname")),
+		codePkg.WithStoriesDirname(viper.GetString("stories-dirname")),
+		// the doctor only reads, the index is left untouched
+		codePkg.WithReadOnlyIndex(),
+	)
+
+	if !dirExists(c.RepositoriesDir()) {
+		report.add("repositories directory", doctorError, fmt.Sprintf("%s does not exist", c.RepositoriesDir()))
+		return nil
+	}
+
+	err := c.Rebuild()
+	var scanErr *codePkg.ScanError
+	if errors.As(err, &scanErr) {
+		for _, err := range scanErr.Errors {
+			report.add("repositories directory", doctorWarning, err.Error())
+		}
+	} else if err != nil {
+		report.add("repositories directory", doctorError, err.Error())
+		return nil
+	}
+	report.add("repositories directory", doctorOK, fmt.Sprintf("%s (%d projects)", c.RepositoriesDir(), len(c.Projects())))
+
+	if !dirExists(c.StoriesDir()) {
+		report.add("stories directory", doctorWarning, fmt.Sprintf("%s does not exist, it's created with the first story", c.StoriesDir()))
+		return c
+	}
+	report.add("stories directory", doctorOK, c.StoriesDir())
+
+	// report the directories of the stories that no longer exist
+	names, err := storyNames()
+	if err != nil {
+		report.add("stories", doctorError, err.Error())
+		return c
+	}
+	entries, err := ioutil.ReadDir(c.StoriesDir())
+	if err != nil {
+		report.add("stories directory", doctorError, err.Error())
+		return c
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if !hasStoryPrefix(names, entry.Name()) {
+			report.add("stories directory", doctorWarning, fmt.Sprintf("%s does not belong to any story", path.Join(c.StoriesDir(), entry.Name())))
+		}
+	}
+
+	return c
+}
+
+// doctorWorktrees reports the worktrees that no longer exist on disk or that
+// belong to a removed story.
+func doctorWorktrees(report *doctorReport, c ifaces.Code) {
+	names, err := storyNames()
+	if err != nil {
+		return
+	}
+
+	var stale int
+	for _, prj := range c.Projects() {
+		v, err := prj.VCS()
+		if err != nil || v.Name() != "git" {
+			continue
+		}
+		worktrees, err := gitWorktrees(prj.Path(nil))
+		if err != nil {
+			report.add("worktrees "+prj.String(), doctorWarning, err.Error())
+			continue
+		}
+		for _, wt := range worktrees {
+			switch {
+			case !fileExists(wt):
+				stale++
+				report.add("worktrees "+prj.String(), doctorWarning, fmt.Sprintf("%s no longer exists, prune it with git worktree prune", wt))
+			case strings.HasPrefix(wt, c.StoriesDir()+"/"):
+				rel := strings.TrimPrefix(wt, c.StoriesDir()+"/")
+				if !hasStoryPrefix(names, rel) {
+					stale++
+					report.add("worktrees "+prj.String(), doctorWarning, fmt.Sprintf("%s belongs to a removed story", wt))
+				}
+			}
+		}
+	}
+	if stale == 0 {
+		report.add("worktrees", doctorOK, "no stale worktrees")
+	}
+}
+
+// gitWorktrees returns the paths of the linked worktrees of the Git
+// repository.
+func gitWorktrees(repositoryPath string) ([]string, error) {
+	gitPath, err := tools.Path(tools.Git)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(gitPath, "worktree", "list", "--porcelain")
+	cmd.Dir = repositoryPath
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing the worktrees")
+	}
+
+	var res []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if strings.HasPrefix(line, "worktree ") {
+			res = append(res, strings.TrimPrefix(line, "worktree "))
+		}
+	}
+	if len(res) == 0 {
+		return nil, nil
+	}
+
+	// the main worktree is always listed first
+	return res[1:], nil
+}
+
+// storyNames returns the names of all the stories.
+func storyNames() ([]string, error) {
+	stories, err := story.List()
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing the stories")
+	}
+	names := make([]string, 0, len(stories))
+	for _, s := range stories {
+		names = append(names, s.GetName())
+	}
+
+	return names, nil
+}
+
+// hasStoryPrefix returns true if p is the directory of one of the stories,
+// or lives within it. Story names may contain slashes.
+func hasStoryPrefix(names []string, p string) bool {
+	for _, name := range names {
+		if p == name || strings.HasPrefix(p, name+"/") || strings.HasPrefix(name, p+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func fileExists(p string) bool {
+	_, err := os.Stat(p)
+	return err == nil
+}
+
+func dirExists(p string) bool {
+	fi, err := os.Stat(p)
+	return err == nil && fi.IsDir()
+}
@@ -8,8 +8,9 @@ import (
 )
 
 var genDocCmd = &cobra.Command{
-	Use:   "gen-doc",
-	Short: "Generate documentation for the command",
+	Use:         "gen-doc",
+	Short:       "Generate documentation for the command",
+	Annotations: map[string]string{skipSetupAnnotation: "true"},
 }
 
 func init() {
@@ -10,9 +10,10 @@ import (
 )
 
 var initConfigCmd = &cobra.Command{
-	Use:   "init-config",
-	Short: "Initialize the configuration file",
-	RunE:  initConfigRun,
+	Use:         "init-config",
+	Short:       "Initialize the configuration file",
+	Annotations: map[string]string{skipSetupAnnotation: "true"},
+	RunE:        initConfigRun,
 }
 
 func init() {
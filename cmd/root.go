@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"log"
 	"os"
 	"path"
 	"strings"
@@ -11,6 +10,8 @@ import (
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+
+	codePkg "github.com/kalbasit/swm/code"
 )
 
 var configPath string
@@ -18,6 +19,21 @@ var version string
 
 var errCodePathIsRequired = errors.New("the code path is required")
 
+// errConfig is the error reading the config file. It's returned by every
+// command except the ones diagnosing the setup.
+var errConfig error
+
+const (
+	// skipSetupAnnotation marks the commands that run without the setup of
+	// the root command (tools and code), along with their sub-commands, they
+	// must not depend on it.
+	skipSetupAnnotation = "swm:skip-setup"
+
+	// skipScanAnnotation marks the commands that scan the code themselves,
+	// the root command creates the code without scanning it.
+	skipScanAnnotation = "swm:skip-scan"
+)
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:     "swm",
@@ -36,7 +52,15 @@ var rootCmd = &cobra.Command{
 		if err := createLogger(cmd); err != nil {
 			return errors.Wrap(err, "error creating a logger")
 		}
-		if err := createCode(); err != nil {
+		if hasAnnotation(cmd, skipSetupAnnotation) {
+			return nil
+		}
+		if errConfig != nil {
+			return errConfig
+		}
+
+		configureTools()
+		if err := createCode(!hasAnnotation(cmd, skipScanAnnotation)); err != nil {
 			return errors.Wrap(err, "error creating a code")
 		}
 
@@ -44,6 +68,18 @@ var rootCmd = &cobra.Command{
 	},
 }
 
+// hasAnnotation returns true if the command, or one of its parents, is
+// marked with the annotation.
+func hasAnnotation(cmd *cobra.Command, annotation string) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		if _, ok := c.Annotations[annotation]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
 func requireCodePath(cmd *cobra.Command, args []string) error {
 	if viper.GetString("code-path") == "" {
 		return errCodePathIsRequired
@@ -89,6 +125,26 @@ func init() {
 		panic(err)
 	}
 
+	rootCmd.PersistentFlags().String("exclude", "", "The pattern matched against the import path of the repositories (and their parent directories) to exclude")
+	if err := viper.BindPFlag("exclude", rootCmd.PersistentFlags().Lookup("exclude")); err != nil {
+		panic(err)
+	}
+
+	rootCmd.PersistentFlags().Int("scan-parallelism", 0, "The number of directories scanned concurrently, defaults to the number of CPUs")
+	if err := viper.BindPFlag("scan-parallelism", rootCmd.PersistentFlags().Lookup("scan-parallelism")); err != nil {
+		panic(err)
+	}
+
+	rootCmd.PersistentFlags().Int("scan-max-depth", 0, "The maximum depth of a repository relative to the repositories directory, zero means no limit")
+	if err := viper.BindPFlag("scan-max-depth", rootCmd.PersistentFlags().Lookup("scan-max-depth")); err != nil {
+		panic(err)
+	}
+
+	rootCmd.PersistentFlags().StringSlice("scan-skip-dirs", codePkg.DefaultSkipDirs, "The names of the directories that are never scanned for repositories")
+	if err := viper.BindPFlag("scan-skip-dirs", rootCmd.PersistentFlags().Lookup("scan-skip-dirs")); err != nil {
+		panic(err)
+	}
+
 	rootCmd.PersistentFlags().String("stories-dirname", "stories", "The name of the stories directory, a child directory of the code-path and the parent directory for all stories")
 	if err := viper.BindPFlag("stories-dirname", rootCmd.PersistentFlags().Lookup("stories-dirname")); err != nil {
 		panic(err)
@@ -112,6 +168,6 @@ func initConfig() {
 
 	// If a config file is found, read it in.
 	if err := viper.ReadInConfig(); err != nil && !os.IsNotExist(err) {
-		log.Fatal(errors.Wrap(err, "error reading the config file"))
+		errConfig = errors.Wrap(err, "error reading the config file")
 	}
 }
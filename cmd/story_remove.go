@@ -5,18 +5,26 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"path/filepath"
 	"strings"
 
+	"github.com/kalbasit/swm/history"
+	"github.com/kalbasit/swm/ifaces"
+	"github.com/kalbasit/swm/project"
 	"github.com/kalbasit/swm/story"
+	"github.com/kalbasit/swm/tmux"
 	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
+
+	vcsPkg "github.com/kalbasit/swm/vcs"
 )
 
 var codeStoryRemoveCmd = &cobra.Command{
 	Use:     "remove",
 	Aliases: []string{"delete"},
 	Short:   "Remove a new story",
-	PreRunE: func(cmd *cobra.Command, args []string) error { return createCode() },
+	PreRunE: requireCodePath,
 	RunE:    codeStoryRemoveRun,
 }
 
@@ -42,7 +50,23 @@ func codeStoryRemoveRun(cmd *cobra.Command, args []string) error {
 		return errors.Wrap(err, "error getting the value of the --force flag")
 	}
 
+	s, err := story.Load(sn)
+	if err != nil {
+		return errors.Wrap(err, "error loading the story")
+	}
+	prjs, err := storyProjects(s)
+	if err != nil {
+		return err
+	}
+
 	if !force {
+		if dirty := dirtyWorkspaces(s, prjs); len(dirty) > 0 {
+			fmt.Printf("The following projects have uncommitted changes in the story %q:\n", sn)
+			for _, prj := range dirty {
+				fmt.Printf("  - %s\n", prj)
+			}
+		}
+
 		tty := bufio.NewReader(os.Stdin)
 		fmt.Printf("Are you sure you want to remove the story %q and all its files? ", sn)
 		text, err := tty.ReadString('\n')
@@ -57,9 +81,11 @@ func codeStoryRemoveRun(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	s, err := story.Load(sn)
-	if err != nil {
-		return errors.Wrap(err, "error loading the story")
+	// remove the workspace of every project of the story from its repository
+	for _, prj := range prjs {
+		if err := prj.RemoveStory(s); err != nil {
+			log.Warn().Err(err).Str("import-path", prj.String()).Msg("error removing the story of the project")
+		}
 	}
 
 	if err := os.RemoveAll(path.Join(code.StoriesDir(), s.GetName())); err != nil {
@@ -70,7 +96,66 @@ func codeStoryRemoveRun(cmd *cobra.Command, args []string) error {
 		return errors.Wrap(err, "error removing the story")
 	}
 
+	if err := history.Remove(s.GetName()); err != nil {
+		log.Warn().Err(err).Msg("error removing the history of the story")
+	}
+
+	if err := tmux.RemoveData(s.GetName()); err != nil {
+		log.Warn().Err(err).Msg("error removing the saved tmux sessions of the story")
+	}
+
 	fmt.Printf("The story %q was removed successfully!\n", sn)
 
 	return nil
 }
+
+// storyProjects returns the projects with a workspace in the directory of
+// the story. The directory is walked instead of using the projects of the
+// code, so the projects matching the exclude pattern are found as well.
+func storyProjects(s ifaces.Story) ([]ifaces.Project, error) {
+	storyDir := path.Join(code.StoriesDir(), s.GetName())
+
+	var res []ifaces.Project
+	err := filepath.Walk(storyDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == storyDir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if !info.IsDir() || p == storyDir || !vcsPkg.IsRepository(p) {
+			return nil
+		}
+		res = append(res, project.New(code, strings.TrimPrefix(p, storyDir+"/")))
+
+		return filepath.SkipDir
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error finding the projects of the story")
+	}
+
+	return res, nil
+}
+
+// dirtyWorkspaces returns the projects with uncommitted changes in the
+// workspace of the story.
+func dirtyWorkspaces(s ifaces.Story, prjs []ifaces.Project) []string {
+	var res []string
+	for _, prj := range prjs {
+		v, err := prj.VCS()
+		if err != nil {
+			log.Warn().Err(err).Str("import-path", prj.String()).Msg("error detecting the version control system of the project")
+			continue
+		}
+		status, err := v.Status(prj.Path(s))
+		if err != nil {
+			log.Warn().Err(err).Str("import-path", prj.String()).Msg("error getting the status of the workspace")
+			continue
+		}
+		if len(status) > 0 {
+			res = append(res, prj.String())
+		}
+	}
+
+	return res
+}
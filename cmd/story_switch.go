@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/kalbasit/swm/ifaces"
+	"github.com/kalbasit/swm/selector"
+	"github.com/kalbasit/swm/story"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var codeStorySwitchCmd = &cobra.Command{
+	Use:   "switch [story] [project]",
+	Short: "Switch to a project of a story",
+	Long: `Switch to a project of a story.
+
+The story, then the project, are selected interactively unless they're given
+as arguments. The project is the import path of the project, a unique suffix
+of it such as owner/repo, or a fuzzy query with a single best match.
+
+Each story has its own tmux server. Within tmux, the client switches to the
+session if it's on the same server, otherwise the client is replaced by a
+client attached to the server of the story, in the same terminal. The server
+and the session are started if needed.`,
+	Args:    cobra.MaximumNArgs(2),
+	PreRunE: requireCodePath,
+	RunE:    codeStorySwitchRun,
+}
+
+func init() {
+	codeStoryCmd.AddCommand(codeStorySwitchCmd)
+
+	codeStorySwitchCmd.Flags().Bool("kill-pane", false, "kill the TMUX pane after switch client")
+	codeStorySwitchCmd.Flags().String("selector", selector.Auto, fmt.Sprintf("The selector used to pick a story and a project, one of %s", strings.Join(selector.Names(), ", ")))
+	codeStorySwitchCmd.Flags().String("restore", restoreAsk, fmt.Sprintf("Restore the saved sessions when the tmux server of the story is started, one of %s", strings.Join([]string{restoreAsk, restoreAlways, restoreNever}, ", ")))
+}
+
+func codeStorySwitchRun(cmd *cobra.Command, args []string) error {
+	kp, err := cmd.Flags().GetBool("kill-pane")
+	if err != nil {
+		return errors.Wrap(err, "error getting the value of the --kill-pane flag")
+	}
+
+	// the flags are shared with the tmux commands, they're bound to the keys
+	// of the config by the tmux command
+	for _, name := range []string{"selector", "restore"} {
+		if f := cmd.Flags().Lookup(name); f.Changed {
+			viper.Set(name, f.Value.String())
+		}
+	}
+
+	var storyName, query string
+	if len(args) > 0 {
+		storyName = args[0]
+	} else if storyName, err = selectStory(); err != nil {
+		if errors.Is(err, selector.ErrNoSelection) {
+			return nil
+		}
+		return err
+	}
+	if len(args) > 1 {
+		query = args[1]
+	}
+
+	if _, err := story.Load(storyName); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			usageStoryRequired(storyName)
+			os.Exit(1)
+		}
+		return errors.Wrap(err, "error loading the story")
+	}
+
+	if tmuxManager, err = newTmuxManager(storyName); err != nil {
+		return err
+	}
+	defer tmuxManager.Close()
+
+	if err := tmuxManager.SwitchClient(query, kp); err != nil && !errors.Is(err, selector.ErrNoSelection) {
+		return err
+	}
+
+	return nil
+}
+
+// selectStory lets the user select a story, the current story is listed
+// last.
+func selectStory() (string, error) {
+	stories, err := listStories(false)
+	if err != nil {
+		return "", err
+	}
+
+	names := make([]string, 0, len(stories))
+	for _, s := range stories {
+		names = append(names, s.GetName())
+	}
+
+	sel, err := selector.New(viper.GetString("selector"))
+	if err != nil {
+		return "", err
+	}
+
+	return sel.Select(names)
+}
+
+// listStories returns the stories sorted by name, the current story is
+// listed first or last.
+func listStories(currentFirst bool) ([]ifaces.Story, error) {
+	stories, err := story.List()
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing the stories")
+	}
+
+	current := os.Getenv("SWM_STORY_NAME")
+	sort.SliceStable(stories, func(i, j int) bool {
+		ci, cj := stories[i].GetName() == current, stories[j].GetName() == current
+		if ci != cj {
+			return ci == currentFirst
+		}
+		return stories[i].GetName() < stories[j].GetName()
+	})
+
+	return stories, nil
+}
@@ -1,11 +1,16 @@
 package cmd
 
 import (
+	"bufio"
 	"errors"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/fatih/color"
+	"github.com/kalbasit/swm/multiplexer"
+	"github.com/kalbasit/swm/selector"
 	"github.com/kalbasit/swm/tmux"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -13,14 +18,59 @@ import (
 
 var tmuxManager *tmux.Manager
 
+// The modes of restoring the saved sessions.
+const (
+	restoreAsk    = "ask"
+	restoreAlways = "always"
+	restoreNever  = "never"
+)
+
 var tmuxCmd = &cobra.Command{
 	Use:   "tmux",
 	Short: "Manage tmux sessions",
+	Long: `Manage the tmux sessions of the projects of a story. The layouts, the
+multiplexers, the sessions and the servers are described in the README.`,
+	PersistentPostRunE: tmuxPostRunE,
 }
 
 func init() {
 	rootCmd.AddCommand(tmuxCmd)
 
+	tmuxCmd.PersistentFlags().String("selector", selector.Auto, fmt.Sprintf("The selector used to pick a project, one of %s. The auto selector uses fzf if it's installed and the builtin selector otherwise", strings.Join(selector.Names(), ", ")))
+	if err := viper.BindPFlag("selector", tmuxCmd.PersistentFlags().Lookup("selector")); err != nil {
+		panic(err)
+	}
+
+	tmuxCmd.PersistentFlags().String("multiplexer", multiplexer.Tmux, fmt.Sprintf("The multiplexer running the sessions, one of %s. The layouts, the saved sessions and the protected processes are only supported by tmux", strings.Join(multiplexer.Names(), ", ")))
+	if err := viper.BindPFlag("multiplexer", tmuxCmd.PersistentFlags().Lookup("multiplexer")); err != nil {
+		panic(err)
+	}
+
+	tmuxCmd.PersistentFlags().String("session-strategy", tmux.SessionPerProject, fmt.Sprintf("How the projects are mapped to the tmux sessions, one of %s: a session per project, or a window per project in a session named after the story", strings.Join(tmux.SessionStrategies(), ", ")))
+	if err := viper.BindPFlag("session-strategy", tmuxCmd.PersistentFlags().Lookup("session-strategy")); err != nil {
+		panic(err)
+	}
+
+	tmuxCmd.PersistentFlags().String("session-naming", tmux.NamingImportPath, fmt.Sprintf("How the tmux sessions are named after their project, one of %s. The session-aliases key of the config file names the sessions of some projects", strings.Join(tmux.SessionNamings(), ", ")))
+	if err := viper.BindPFlag("session-naming", tmuxCmd.PersistentFlags().Lookup("session-naming")); err != nil {
+		panic(err)
+	}
+
+	tmuxCmd.PersistentFlags().String("socket-dir", "", "The directory of the sockets of the tmux servers, the socket directory of tmux by default")
+	if err := viper.BindPFlag("socket-dir", tmuxCmd.PersistentFlags().Lookup("socket-dir")); err != nil {
+		panic(err)
+	}
+
+	tmuxCmd.PersistentFlags().String("tmux-config", "", "The tmux config file of the servers of the stories, unless the directory of the story or the code has a .tmux.conf")
+	if err := viper.BindPFlag("tmux-config", tmuxCmd.PersistentFlags().Lookup("tmux-config")); err != nil {
+		panic(err)
+	}
+
+	tmuxCmd.PersistentFlags().String("restore", restoreAsk, fmt.Sprintf("Restore the saved sessions when the tmux server of the story is started, one of %s", strings.Join([]string{restoreAsk, restoreAlways, restoreNever}, ", ")))
+	if err := viper.BindPFlag("restore", tmuxCmd.PersistentFlags().Lookup("restore")); err != nil {
+		panic(err)
+	}
+
 	if err := viper.BindPFlags(tmuxCmd.Flags()); err != nil {
 		panic(fmt.Sprintf("error binding cobra flags to viper: %s", err))
 	}
@@ -36,7 +86,7 @@ func tmuxPreRunE(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	if tmuxManager, err = tmux.New(code, sn); err != nil {
+	if tmuxManager, err = newTmuxManager(sn); err != nil {
 		if errors.Is(err, os.ErrNotExist) {
 			usageStoryRequired(sn)
 			os.Exit(1)
@@ -47,6 +97,138 @@ func tmuxPreRunE(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// newTmuxManager returns the tmux manager of the story, configured from the
+// flags and the config file.
+func newTmuxManager(storyName string) (*tmux.Manager, error) {
+	sel, err := selector.New(viper.GetString("selector"))
+	if err != nil {
+		return nil, err
+	}
+
+	layouts, err := layoutConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []tmux.Option{
+		tmux.WithSelector(sel),
+		tmux.WithLayouts(layouts),
+		tmux.WithSessionStrategy(viper.GetString("session-strategy")),
+		tmux.WithSessionNaming(viper.GetString("session-naming")),
+	}
+	switch mode := viper.GetString("restore"); mode {
+	case restoreAsk:
+		opts = append(opts, tmux.WithRestorePrompt(askRestore))
+	case restoreAlways:
+		opts = append(opts, tmux.WithRestorePrompt(func(*tmux.Snapshot) (bool, error) { return true, nil }))
+	case restoreNever:
+	default:
+		return nil, fmt.Errorf("unknown restore mode %q", mode)
+	}
+	if viper.IsSet("restore-commands") {
+		opts = append(opts, tmux.WithRestoreCommands(viper.GetStringSlice("restore-commands")...))
+	}
+	if name := viper.GetString("multiplexer"); name != multiplexer.Tmux {
+		mux, err := multiplexer.New(name, storyName)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, tmux.WithMultiplexer(mux))
+	}
+	sopts, err := serverOptions()
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, sopts...)
+	if f := viper.GetString("tmux-config"); f != "" {
+		opts = append(opts, tmux.WithConfigFile(f))
+	}
+	aliases, err := sessionAliasesConfig()
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, tmux.WithSessionAliases(aliases))
+
+	return tmux.New(code, storyName, opts...)
+}
+
+// sessionAliasesConfig returns the names of the sessions of the config file,
+// by import path.
+func sessionAliasesConfig() (map[string]string, error) {
+	var entries []struct {
+		Project string `mapstructure:"project"`
+		Alias   string `mapstructure:"alias"`
+	}
+	if err := viper.UnmarshalKey("session-aliases", &entries); err != nil {
+		return nil, fmt.Errorf("error reading the session aliases: %w", err)
+	}
+
+	aliases := make(map[string]string, len(entries))
+	for _, e := range entries {
+		if e.Project == "" || e.Alias == "" {
+			return nil, fmt.Errorf("the session alias %q of %q requires a project and an alias", e.Alias, e.Project)
+		}
+		aliases[e.Project] = e.Alias
+	}
+
+	return aliases, nil
+}
+
+// serverOptions returns the options of the tmux servers shared by the tmux
+// commands, the socket directory and the protected processes.
+func serverOptions() ([]tmux.Option, error) {
+	opts, err := protectedProcessesConfig()
+	if err != nil {
+		return nil, err
+	}
+	if dir := viper.GetString("socket-dir"); dir != "" {
+		opts = append(opts, tmux.WithSocketDir(dir))
+	}
+
+	return opts, nil
+}
+
+// protectedProcessesConfig returns the option setting the protected
+// processes of the config file, if any.
+func protectedProcessesConfig() ([]tmux.Option, error) {
+	if !viper.IsSet("protected-processes") {
+		return nil, nil
+	}
+
+	var processes []tmux.ProtectedProcess
+	if err := viper.UnmarshalKey("protected-processes", &processes); err != nil {
+		return nil, fmt.Errorf("error reading the protected processes: %w", err)
+	}
+
+	return []tmux.Option{tmux.WithProtectedProcesses(processes)}, nil
+}
+
+func tmuxPostRunE(cmd *cobra.Command, args []string) error {
+	if tmuxManager == nil {
+		return nil
+	}
+
+	return tmuxManager.Close()
+}
+
+// askRestore asks the user whether the saved sessions should be restored, it
+// declines if the standard input is not a terminal.
+func askRestore(s *tmux.Snapshot) (bool, error) {
+	if fi, err := os.Stdin.Stat(); err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return false, nil
+	}
+
+	fmt.Printf("Restore the %d sessions saved on %s? [y/N] ", len(s.Sessions), s.CreatedAt.Format(time.RFC1123))
+	text, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false, err
+	}
+
+	ans := strings.TrimSpace(text)
+
+	return strings.EqualFold(ans, "y") || strings.EqualFold(ans, "yes"), nil
+}
+
 func usageStoryRequired(sn string) {
 	c := color.New(color.FgRed).Add(color.Bold)
 	c.Printf("A story is required, but none was created with the name %q. In order to create or attach TMUX sessions, you must create a session with same name. You can do so with the command: swm story create\n\n", sn)
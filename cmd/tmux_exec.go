@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/kalbasit/swm/tmux"
+	"github.com/olekukonko/tablewriter"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var tmuxExecCmd = &cobra.Command{
+	Use:   "exec [flags] -- command [args...]",
+	Short: "Run a command in every session of the tmux server of this story",
+	Long: `Run a command in every session of the tmux server of this story.
+
+The command line is typed, followed by Enter, in the active pane of every
+running session of the story, the window of the project with --session-strategy
+window. The sessions are selected by the import path of their project with
+--project, a glob where ** matches any number of directories:
+
+  swm tmux exec --project 'github.com/owner/**' -- go get -u ./...
+
+The arguments are quoted for the shell, a single argument is the command line
+itself:
+
+  swm tmux exec -- 'make && make install'
+
+A pane running a command in the foreground, such as an editor, is busy: the
+command is not sent to it and the pane is reported as skipped. With
+--new-window, the command runs in a new window of every session, in the
+directory of the project, and no session is skipped.`,
+	Args:    cobra.MinimumNArgs(1),
+	PreRunE: tmuxPreRunE,
+	RunE:    tmuxExecRun,
+}
+
+func init() {
+	tmuxCmd.AddCommand(tmuxExecCmd)
+
+	tmuxExecCmd.Flags().String("story-name", os.Getenv("SWM_STORY_NAME"), "The name of the story")
+	tmuxExecCmd.Flags().StringP("project", "p", "", "Only run the command in the sessions of the projects matching the glob")
+	tmuxExecCmd.Flags().Bool("new-window", false, "Run the command in a new window of every session")
+	tmuxExecCmd.Flags().StringP("output", "o", outputTable, fmt.Sprintf("The format of the output, one of %s", strings.Join([]string{outputTable, outputJSON}, ", ")))
+}
+
+func tmuxExecRun(cmd *cobra.Command, args []string) error {
+	var (
+		opts tmux.ExecOptions
+		err  error
+	)
+	if opts.Pattern, err = cmd.Flags().GetString("project"); err != nil {
+		return errors.Wrap(err, "error getting the value of the --project flag")
+	}
+	if opts.NewWindow, err = cmd.Flags().GetBool("new-window"); err != nil {
+		return errors.Wrap(err, "error getting the value of the --new-window flag")
+	}
+	output, err := cmd.Flags().GetString("output")
+	if err != nil {
+		return errors.Wrap(err, "error getting the value of the --output flag")
+	}
+	if output != outputTable && output != outputJSON {
+		return errors.Errorf("unknown output format %q", output)
+	}
+
+	results, err := tmuxManager.Exec(tmux.CommandLine(args), opts)
+	if err != nil {
+		return err
+	}
+
+	if output == outputJSON {
+		if results == nil {
+			results = []*tmux.ExecResult{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	}
+
+	if len(results) == 0 {
+		fmt.Println("No running session matches")
+		return nil
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetAutoWrapText(false)
+	table.SetAutoFormatHeaders(false)
+	table.SetHeader([]string{"Session", "Project", "Pane", "Status"})
+	for _, r := range results {
+		status := "sent"
+		if r.Busy != "" {
+			status = fmt.Sprintf("skipped, busy with %s", r.Busy)
+		}
+		table.Append([]string{r.Session, r.Project, r.Pane, status})
+	}
+	table.Render()
+
+	return nil
+}
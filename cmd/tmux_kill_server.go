@@ -8,8 +8,37 @@ import (
 )
 
 var tmuxKillServerCmd = &cobra.Command{
-	Use:     "kill-server",
-	Short:   "Kill the server closes the tmux session for this profile and story",
+	Use:   "kill-server",
+	Short: "Kill the server closes the tmux session for this profile and story",
+	Long: `Kill the server closes the tmux session for this profile and story.
+
+The server is not killed if a protected process, such as an editor, a test
+suite or a remote shell, is running in the foreground of a pane. The panes
+running them are reported. With --graceful, the protected processes are
+asked to exit with their exit keys and the server is killed once they all
+exited. The processes still running once their timeout expires are
+reported and the server is left running. Nothing is asked to exit if one of
+the processes has no exit keys.
+
+The protected processes are configured under the protected-processes key of
+the config file, they replace the default ones. The match pattern is a
+regular expression matched against the command line of the process, with
+the path of the program stripped.
+
+  protected-processes:
+    - name: vim
+      match: ^n?vim( |$)
+      exit-keys: ["Escape", ":mksession! {vim-session}", "Enter", ":xa", "Enter"]
+    - name: test suite
+      match: ^(go|cargo) test( |$)
+      exit-keys: ["C-c"]
+      timeout: 10s
+    - name: remote shell
+      match: ^ssh( |$)
+
+The exit keys are sent with tmux send-keys, {vim-session} is replaced by the
+path of the vim session file reopened the next time the project is started.
+The timeout defaults to 5s.`,
 	PreRunE: tmuxPreRunE,
 	RunE:    tmuxKillServerRun,
 }
@@ -18,14 +47,22 @@ func init() {
 	tmuxCmd.AddCommand(tmuxKillServerCmd)
 
 	tmuxKillServerCmd.Flags().String("story-name", os.Getenv("SWM_STORY_NAME"), "The name of the story")
+	tmuxKillServerCmd.Flags().Bool("graceful", false, "if a protected process is found running, ask it to exit")
 	tmuxKillServerCmd.Flags().Bool("vim-exit", false, "if vim is found running, kill it")
+	if err := tmuxKillServerCmd.Flags().MarkDeprecated("vim-exit", "use --graceful instead"); err != nil {
+		panic(err)
+	}
 }
 
 func tmuxKillServerRun(cmd *cobra.Command, args []string) error {
+	graceful, err := cmd.Flags().GetBool("graceful")
+	if err != nil {
+		return errors.Wrap(err, "error getting the value of the --graceful flag")
+	}
 	ve, err := cmd.Flags().GetBool("vim-exit")
 	if err != nil {
 		return errors.Wrap(err, "error getting the value of the --vim-exit flag")
 	}
 
-	return tmuxManager.KillServer(ve)
+	return tmuxManager.KillServer(graceful || ve)
 }
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/kalbasit/swm/story"
+	"github.com/kalbasit/swm/tmux"
+	"github.com/olekukonko/tablewriter"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// The formats of the output of tmux ls.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+var tmuxLsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List the tmux servers of all the stories and their sessions",
+	Long: `List the tmux servers of all the stories and their sessions.
+
+The servers are found in the socket directory of tmux, $TMUX_TMPDIR or /tmp.
+For every session, the number of attached clients, the number of windows,
+the last activity and the panes running a protected process (see swm tmux
+kill-server) are reported. The sockets left behind by a server that is no
+longer running are reported as not running.`,
+	RunE: tmuxLsRun,
+}
+
+func init() {
+	tmuxCmd.AddCommand(tmuxLsCmd)
+
+	tmuxLsCmd.Flags().StringP("output", "o", outputTable, fmt.Sprintf("The format of the output, one of %s", strings.Join([]string{outputTable, outputJSON}, ", ")))
+}
+
+func tmuxLsRun(cmd *cobra.Command, args []string) error {
+	output, err := cmd.Flags().GetString("output")
+	if err != nil {
+		return errors.Wrap(err, "error getting the value of the --output flag")
+	}
+	if output != outputTable && output != outputJSON {
+		return errors.Errorf("unknown output format %q", output)
+	}
+
+	stories, err := story.List()
+	if err != nil {
+		return errors.Wrap(err, "error listing the stories")
+	}
+	storyNames := make([]string, 0, len(stories))
+	for _, s := range stories {
+		storyNames = append(storyNames, s.GetName())
+	}
+
+	opts, err := serverOptions()
+	if err != nil {
+		return err
+	}
+	servers, err := tmux.ListServers(storyNames, opts...)
+	if err != nil {
+		return err
+	}
+
+	if output == outputJSON {
+		if servers == nil {
+			servers = []*tmux.Server{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(servers)
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetAutoWrapText(false)
+	table.SetAutoFormatHeaders(false)
+	table.SetHeader([]string{"Story", "Socket", "Session", "Clients", "Windows", "Last activity", "Protected processes"})
+	for _, srv := range servers {
+		if !srv.Running {
+			table.Append([]string{srv.Story, srv.Socket, "not running", "", "", "", ""})
+			continue
+		}
+		for _, s := range srv.Sessions {
+			busy := make([]string, 0, len(s.BusyPanes))
+			for _, pane := range s.BusyPanes {
+				busy = append(busy, fmt.Sprintf("%s (%s)", pane.Pane, pane.Process))
+			}
+			table.Append([]string{
+				srv.Story,
+				srv.Socket,
+				s.Name,
+				strconv.Itoa(s.Clients),
+				strconv.Itoa(s.Windows),
+				s.LastActivity.Format("Mon Jan 2 2006 at 15:04"),
+				strings.Join(busy, ", "),
+			})
+		}
+	}
+	table.Render()
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kalbasit/swm/tmux"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var tmuxReapCmd = &cobra.Command{
+	Use:   "reap",
+	Short: "Kill the tmux servers of the stories that have been idle for a while",
+	Long: `Kill the tmux servers of the stories that have been idle for a while.
+
+A server is idle if none of its sessions had any activity for the --idle
+duration. The sessions of an idle server are saved, to be restored the next
+time the story is started, and its protected processes are asked to exit
+with their exit keys (see swm tmux kill-server) before the server is
+killed. The servers whose protected processes refuse to exit are left
+running and reported. The server outside of any story is never reaped, nor
+are the servers with an attached client unless --include-attached is set.`,
+	PreRunE: requireCodePath,
+	RunE:    tmuxReapRun,
+}
+
+func init() {
+	tmuxCmd.AddCommand(tmuxReapCmd)
+
+	tmuxReapCmd.Flags().Duration("idle", 72*time.Hour, "How long the sessions of a server must have been idle for it to be reaped")
+	tmuxReapCmd.Flags().Bool("dry-run", false, "Only print the servers that would be reaped")
+	tmuxReapCmd.Flags().Bool("include-attached", false, "Also reap the idle servers with an attached client")
+}
+
+func tmuxReapRun(cmd *cobra.Command, args []string) error {
+	idle, err := cmd.Flags().GetDuration("idle")
+	if err != nil {
+		return errors.Wrap(err, "error getting the value of the --idle flag")
+	}
+	if idle <= 0 {
+		return errors.New("the --idle duration must be positive")
+	}
+	dryRun, err := cmd.Flags().GetBool("dry-run")
+	if err != nil {
+		return errors.Wrap(err, "error getting the value of the --dry-run flag")
+	}
+	includeAttached, err := cmd.Flags().GetBool("include-attached")
+	if err != nil {
+		return errors.Wrap(err, "error getting the value of the --include-attached flag")
+	}
+
+	stories, err := listStories(false)
+	if err != nil {
+		return err
+	}
+	storyNames := make([]string, 0, len(stories))
+	for _, s := range stories {
+		storyNames = append(storyNames, s.GetName())
+	}
+
+	opts, err := serverOptions()
+	if err != nil {
+		return err
+	}
+	servers, err := tmux.ListServers(storyNames, opts...)
+	if err != nil {
+		return err
+	}
+
+	var failed int
+	for _, srv := range tmux.IdleServers(servers, idle, includeAttached) {
+		since := fmt.Sprintf("idle since %s", srv.LastActivity().Format("Mon Jan 2 2006 at 15:04"))
+		if srv.LastActivity().IsZero() {
+			since = "no session"
+		}
+		if dryRun {
+			fmt.Printf("Would reap the server of the story %s (%s)\n", srv.Story, since)
+			continue
+		}
+
+		if err := reapServer(srv.Story); err != nil {
+			fmt.Printf("Could not reap the server of the story %s: %s\n", srv.Story, err)
+			failed++
+			continue
+		}
+		fmt.Printf("Reaped the server of the story %s (%s)\n", srv.Story, since)
+	}
+
+	if failed > 0 {
+		return errors.Errorf("%d servers could not be reaped", failed)
+	}
+
+	return nil
+}
+
+// reapServer saves the sessions of the server of the story, asks its
+// protected processes to exit and kills it. The servers are the ones of
+// tmux, the multiplexer of the config file is not used.
+func reapServer(storyName string) error {
+	opts, err := serverOptions()
+	if err != nil {
+		return err
+	}
+	opts = append(opts,
+		tmux.WithSessionStrategy(viper.GetString("session-strategy")),
+		tmux.WithSessionNaming(viper.GetString("session-naming")),
+	)
+
+	m, err := tmux.New(code, storyName, opts...)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	return m.Kill(true)
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var tmuxRecentCmd = &cobra.Command{
+	Use:     "recent",
+	Short:   "Print the running and the recently visited projects, ordered by frecency",
+	PreRunE: tmuxPreRunE,
+	RunE:    tmuxRecentRun,
+}
+
+func init() {
+	tmuxCmd.AddCommand(tmuxRecentCmd)
+
+	tmuxRecentCmd.Flags().String("story-name", os.Getenv("SWM_STORY_NAME"), "The name of the story")
+}
+
+func tmuxRecentRun(cmd *cobra.Command, args []string) error {
+	recent, err := tmuxManager.Recent()
+	if err != nil {
+		return err
+	}
+
+	for _, rp := range recent {
+		mark := "  "
+		if rp.Running {
+			mark = "* "
+		}
+		fmt.Printf("%s%s\n", mark, rp.Project)
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var tmuxRestoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "Restore the saved sessions of the tmux server of this story",
+	Long: `Restore the saved sessions of the tmux server of this story.
+
+The sessions that are running are kept as is. The windows, the layout of
+their panes and the working directories are restored. The commands running
+in the panes are only started again if their program is one of the
+restore-commands of the config file, it defaults to a list of editors,
+pagers and interactive clients.`,
+	PreRunE: tmuxPreRunE,
+	RunE:    tmuxRestoreRun,
+}
+
+func init() {
+	tmuxCmd.AddCommand(tmuxRestoreCmd)
+
+	tmuxRestoreCmd.Flags().String("story-name", os.Getenv("SWM_STORY_NAME"), "The name of the story")
+}
+
+func tmuxRestoreRun(cmd *cobra.Command, args []string) error {
+	restored, err := tmuxManager.Restore()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Restored %d sessions\n", len(restored))
+	for _, name := range restored {
+		fmt.Printf("  - %s\n", name)
+	}
+
+	return nil
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var tmuxSaveCmd = &cobra.Command{
+	Use:     "save",
+	Short:   "Save the sessions of the tmux server of this story to restore them later",
+	PreRunE: tmuxPreRunE,
+	RunE:    tmuxSaveRun,
+}
+
+func init() {
+	tmuxCmd.AddCommand(tmuxSaveCmd)
+
+	tmuxSaveCmd.Flags().String("story-name", os.Getenv("SWM_STORY_NAME"), "The name of the story")
+}
+
+func tmuxSaveRun(cmd *cobra.Command, args []string) error {
+	s, err := tmuxManager.Save()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Saved %d sessions\n", len(s.Sessions))
+
+	return nil
+}
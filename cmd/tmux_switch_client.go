@@ -3,13 +3,27 @@ package cmd
 import (
 	"os"
 
+	"github.com/kalbasit/swm/selector"
+	"github.com/kalbasit/swm/tmux"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 var tmuxSwitchClientCmd = &cobra.Command{
-	Use:     "switch-client",
-	Short:   "Switch the client within the session for this profile and story",
+	Use:   "switch-client [project]",
+	Short: "Switch the client within the session for this profile and story",
+	Long: `Switch the client within the session for this profile and story.
+
+The project is selected interactively unless it's given as an argument. The
+argument is the import path of the project, a unique suffix of it such as
+owner/repo, or a fuzzy query with a single best match.
+
+With --all-stories, the project is selected among the worktrees of every
+story, listed as story › project, and the repositories outside of any
+story. The session is started on the tmux server of the selected story, and
+the terminal is attached to it.`,
+	Args:    cobra.MaximumNArgs(1),
 	PreRunE: tmuxPreRunE,
 	RunE:    tmuxSwitchClientRun,
 }
@@ -19,6 +33,7 @@ func init() {
 
 	tmuxSwitchClientCmd.Flags().String("story-name", os.Getenv("SWM_STORY_NAME"), "The name of the story")
 	tmuxSwitchClientCmd.Flags().Bool("kill-pane", false, "kill the TMUX pane after switch client")
+	tmuxSwitchClientCmd.Flags().Bool("all-stories", false, "select the project among the worktrees of every story")
 }
 
 func tmuxSwitchClientRun(cmd *cobra.Command, args []string) error {
@@ -27,5 +42,51 @@ func tmuxSwitchClientRun(cmd *cobra.Command, args []string) error {
 		return errors.Wrap(err, "error getting the value of the --kill-pane flag")
 	}
 
-	return tmuxManager.SwitchClient(kp)
+	all, err := cmd.Flags().GetBool("all-stories")
+	if err != nil {
+		return errors.Wrap(err, "error getting the value of the --all-stories flag")
+	}
+
+	var query string
+	if len(args) > 0 {
+		query = args[0]
+	}
+
+	if all {
+		if query != "" {
+			return errors.New("the project cannot be given with --all-stories")
+		}
+
+		stories, err := listStories(true)
+		if err != nil {
+			return err
+		}
+		sel, err := selector.New(viper.GetString("selector"))
+		if err != nil {
+			return err
+		}
+		entry, err := sel.Select(tmux.StoryEntries(code, stories))
+		if err != nil {
+			if errors.Is(err, selector.ErrNoSelection) {
+				return nil
+			}
+			return err
+		}
+
+		// the session is started on the server of the selected story
+		var sn string
+		sn, query = tmux.ParseStoryEntry(entry)
+		if err := tmuxManager.Close(); err != nil {
+			return err
+		}
+		if tmuxManager, err = newTmuxManager(sn); err != nil {
+			return err
+		}
+	}
+
+	if err := tmuxManager.SwitchClient(query, kp); err != nil && !errors.Is(err, selector.ErrNoSelection) {
+		return err
+	}
+
+	return nil
 }
@@ -5,8 +5,16 @@ import (
 )
 
 var tmuxVimExitCmd = &cobra.Command{
-	Use:     "vim-exit",
-	Short:   "Close all of open Vim within the session for this profile and story",
+	Use:   "vim-exit",
+	Short: "Close all of open Vim within the session for this profile and story",
+	Long: `Close all of open Vim within the session for this profile and story.
+
+The session of each Vim is saved with :mksession before it exits, and it's
+reopened the next time the project is started. The panes where Vim refuses
+to exit, for instance because of a buffer without a name, are reported.
+
+Vim is asked to exit with the exit keys of the protected process named vim,
+see swm tmux kill-server.`,
 	PreRunE: tmuxPreRunE,
 	RunE:    tmuxVimExitRun,
 }
@@ -10,6 +10,8 @@ import (
 
 	"github.com/google/go-github/github"
 	"github.com/kalbasit/swm/ifaces"
+	"github.com/kalbasit/swm/layout"
+	"github.com/kalbasit/swm/tools"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -78,7 +80,36 @@ func createGithubClient() error {
 	return nil
 }
 
-func createCode() error {
+// configureTools applies the paths of the tools configured under the tools
+// key of the config file.
+func configureTools() {
+	for name, p := range viper.GetStringMapString("tools") {
+		if err := tools.SetPath(name, p); err != nil {
+			log.Warn().Err(err).Msg("error configuring the tools")
+		}
+	}
+}
+
+// layoutConfig returns the layouts of the tmux sessions configured under the
+// layout, layouts and layout-rules keys of the config file.
+func layoutConfig() (*layout.Config, error) {
+	c := &layout.Config{Default: viper.GetString("layout")}
+	if err := viper.UnmarshalKey("layouts", &c.Layouts); err != nil {
+		return nil, errors.Wrap(err, "error reading the layouts")
+	}
+	if err := viper.UnmarshalKey("layout-rules", &c.Rules); err != nil {
+		return nil, errors.Wrap(err, "error reading the layout rules")
+	}
+	if err := c.Validate(); err != nil {
+		return nil, errors.Wrap(err, "error validating the layouts")
+	}
+
+	return c, nil
+}
+
+// createCode creates the code from the configuration and scans it, unless
+// scan is false.
+func createCode(scan bool) error {
 	log.Logger.Debug().Msg("creating a new coder")
 
 	var ignorePattern *regexp.Regexp
@@ -91,7 +122,31 @@ func createCode() error {
 		}
 	}
 
-	code = codePkg.New(viper.GetString("code-path"), ignorePattern)
+	code = codePkg.New(viper.GetString("code-path"), ignorePattern,
+		codePkg.WithParallelism(viper.GetInt("scan-parallelism")),
+		codePkg.WithMaxDepth(viper.GetInt("scan-max-depth")),
+		codePkg.WithSkipDirs(viper.GetStringSlice("scan-skip-dirs")...),
+		codePkg.WithRepositoriesDirname(viper.GetString("repositories-dirname")),
+		codePkg.WithStoriesDirname(viper.GetString("stories-dirname")),
+	)
+	if !scan {
+		return nil
+	}
+
+	return warnScanErrors(code.Scan())
+}
+
+// warnScanErrors logs the directories that could not be scanned as warnings,
+// they should not prevent the usage of the projects that were found. Any
+// other error is returned as is.
+func warnScanErrors(err error) error {
+	var scanErr *codePkg.ScanError
+	if !errors.As(err, &scanErr) {
+		return err
+	}
+	for _, err := range scanErr.Errors {
+		log.Warn().Err(err).Msg("error scanning the code")
+	}
 
-	return code.Scan()
+	return nil
 }
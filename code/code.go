@@ -1,18 +1,17 @@
 package code
 
 import (
-	"bytes"
 	"fmt"
-	"io/ioutil"
 	"os"
-	"os/exec"
 	"path"
 	"regexp"
+	"runtime"
 	"strings"
 	"sync"
 
 	"github.com/kalbasit/swm/ifaces"
 	"github.com/kalbasit/swm/project"
+	"github.com/kalbasit/swm/vcs"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 )
@@ -34,58 +33,199 @@ var (
 	ErrCoderNotScanned = errors.New("the code was not scanned")
 
 	// ErrDotGitMalformed is returned if .git is malformed
-	ErrDotGitMalformed = errors.New(".git is malformed")
-
-	gitWorktreeRootRegex = regexp.MustCompile(`^gitdir: (.*)/\.git/worktrees/.*$`)
-
-	// gitPath is the PATH to the git binary
-	gitPath string
+	ErrDotGitMalformed = vcs.ErrDotGitMalformed
 )
 
-func init() {
-	var err error
-	gitPath, err = exec.LookPath("git")
-	if err != nil {
-		log.Fatal().Msgf("error looking up the git executable, is it installed? %s", err)
-	}
-}
-
 // code implements the coder interface
 type code struct {
 	// path is the base path of this profile
 	path string
 
-	// excludePattern is a list of patterns to ignore
+	// excludePattern is matched against the import path of every directory
+	// being scanned, the matching directories and everything below them are
+	// ignored.
 	excludePattern *regexp.Regexp
 
+	// parallelism is the number of directories that are scanned concurrently
+	parallelism int
+
+	// maxDepth is the maximum depth of a project relative to the repositories
+	// directory, zero means no limit.
+	maxDepth int
+
+	// skipDirs is the list of directory names that are never scanned
+	skipDirs []string
+
+	// repositoriesDirname is the name of the repositories directory
+	repositoriesDirname string
+
+	// storiesDirname is the name of the stories directory
+	storiesDirname string
+
+	// readOnlyIndex is true if the scans never save the index
+	readOnlyIndex bool
+
 	mu       sync.RWMutex
 	projects map[string]ifaces.Project
 }
 
+// Option configures a code returned by New.
+type Option func(*code)
+
+// WithParallelism sets the number of directories that are scanned
+// concurrently. It defaults to the number of CPUs.
+func WithParallelism(n int) Option {
+	return func(c *code) {
+		if n > 0 {
+			c.parallelism = n
+		}
+	}
+}
+
+// WithMaxDepth sets the maximum depth of a project relative to the
+// repositories directory, zero means no limit.
+func WithMaxDepth(n int) Option {
+	return func(c *code) { c.maxDepth = n }
+}
+
+// WithSkipDirs sets the list of directory names that are never scanned. It
+// defaults to DefaultSkipDirs.
+func WithSkipDirs(names ...string) Option {
+	return func(c *code) { c.skipDirs = names }
+}
+
+// WithRepositoriesDirname sets the name of the repositories directory, a child
+// directory of the code path and the parent directory for all repositories.
+func WithRepositoriesDirname(name string) Option {
+	return func(c *code) {
+		if name != "" {
+			c.repositoriesDirname = name
+		}
+	}
+}
+
+// WithStoriesDirname sets the name of the stories directory, a child directory
+// of the code path and the parent directory for all stories.
+func WithStoriesDirname(name string) Option {
+	return func(c *code) {
+		if name != "" {
+			c.storiesDirname = name
+		}
+	}
+}
+
+// WithReadOnlyIndex keeps the results of the scans in memory, the index is
+// read by Scan but never saved.
+func WithReadOnlyIndex() Option {
+	return func(c *code) { c.readOnlyIndex = true }
+}
+
 // New returns a new empty Code, caller must call Load to load from cache or
 // scan the code directory
-func New(p string, ignore *regexp.Regexp) ifaces.Code {
-	return &code{
-		excludePattern: ignore,
-		path:           path.Clean(p),
-		projects:       make(map[string]ifaces.Project),
+func New(p string, ignore *regexp.Regexp, opts ...Option) ifaces.Code {
+	c := &code{
+		excludePattern:      ignore,
+		path:                path.Clean(p),
+		parallelism:         runtime.NumCPU(),
+		skipDirs:            DefaultSkipDirs,
+		repositoriesDirname: "repositories",
+		storiesDirname:      "stories",
+		projects:            make(map[string]ifaces.Project),
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
+
+	return c
 }
 
 // Path returns the absolute path of this coder
 func (c *code) Path() string { return c.path }
 
 // Scan loads the code from the cache (if it exists), otherwise it will
-// initiate a full scan and save it in cache.
+// initiate a full scan and save it in cache. The directories recorded in the
+// cache are validated against the filesystem and only the ones that have
+// changed since the last scan are scanned again.
 func (c *code) Scan() error {
 	// validate the Code, we cannot load an invalid Code
 	if err := c.validate(); err != nil {
 		return err
 	}
 
-	c.scan()
+	idx, err := loadIndex(c.path, c.fingerprint())
+	if err != nil {
+		return err
+	}
+	if idx == nil {
+		log.Debug().Str("path", c.path).Msg("no usable index was found, initiating a full scan")
+		return c.Rebuild()
+	}
 
-	return nil
+	stale := idx.staleDirectories(c.RepositoriesDir())
+	if _, ok := idx.Directories[""]; !ok {
+		stale = []string{""}
+	}
+	if len(stale) == 0 {
+		c.load(idx)
+		return nil
+	}
+
+	log.Debug().Strs("directories", stale).Msg("rescanning the directories that changed since the last scan")
+	for _, dir := range stale {
+		idx.forget(dir)
+	}
+
+	return c.scanAndSave(idx, stale)
+}
+
+// Rebuild ignores the cache and initiates a full scan of the code, saving
+// the result in cache.
+func (c *code) Rebuild() error {
+	// validate the Code, we cannot load an invalid Code
+	if err := c.validate(); err != nil {
+		return err
+	}
+
+	return c.scanAndSave(newIndex(c.path, c.fingerprint()), []string{""})
+}
+
+// scanAndSave scans the directories into the index, loads the projects and
+// saves the index unless it's read-only. A *ScanError is returned after the
+// index is saved if some directories could not be scanned.
+func (c *code) scanAndSave(idx *index, dirs []string) error {
+	scanErr := c.scan(idx, dirs)
+	c.load(idx)
+
+	if c.readOnlyIndex {
+		return scanErr
+	}
+	if err := idx.save(); err != nil {
+		return err
+	}
+
+	return scanErr
+}
+
+// fingerprint returns a string describing the settings that affect the
+// result of a scan, an index created with different settings is discarded.
+func (c *code) fingerprint() string {
+	var exclude string
+	if c.excludePattern != nil {
+		exclude = c.excludePattern.String()
+	}
+
+	return fmt.Sprintf("repositories-dirname=%s max-depth=%d skip-dirs=%s exclude=%s", c.repositoriesDirname, c.maxDepth, strings.Join(c.skipDirs, ","), exclude)
+}
+
+// load replaces the projects of this code with the projects of the index.
+func (c *code) load(idx *index) {
+	c.mu.Lock()
+	c.projects = make(map[string]ifaces.Project)
+	c.mu.Unlock()
+
+	for _, importPath := range idx.Projects {
+		c.addProject(importPath)
+	}
 }
 
 // Projects returns all the projects that are available for this story as
@@ -104,7 +244,14 @@ func (c *code) Projects() []ifaces.Project {
 	return res
 }
 
-func (c *code) Clone(url string) error {
+// Clone clones url as the new project using the named version control
+// system, the default one is used if vcsName is empty.
+func (c *code) Clone(url, vcsName string) error {
+	v, err := vcs.Get(vcsName)
+	if err != nil {
+		return err
+	}
+
 	// compute the import path of this URL
 	var importPath string
 	{
@@ -144,15 +291,13 @@ func (c *code) Clone(url string) error {
 	tmpDir := path.Join(c.path, ".tmp-clone", strings.Replace(prj.String(), "/", "_", -1))
 
 	// Clone the project in its temporary location
-	cmd := exec.Command(gitPath, "clone", url, tmpDir)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
+	if err := v.Clone(url, tmpDir); err != nil {
 		return err
 	}
 	log.Debug().
 		Str("import-path", prj.String()).
 		Str("clone_at", tmpDir).
+		Str("vcs", v.Name()).
 		Msg("project successfully cloned")
 
 	// move the directory to its real location
@@ -189,25 +334,14 @@ func (c *code) GetProjectByRelativePath(importPath string) (ifaces.Project, erro
 // GetProjectByAbsolutePath returns the project corresponding to the absolute
 // path.
 func (c *code) GetProjectByAbsolutePath(p string) (ifaces.Project, error) {
-	dotGit := path.Join(p, ".git")
-	gitInfo, err := os.Stat(dotGit)
+	v, err := vcs.Detect(p)
 	if err != nil {
-		return nil, errors.Wrap(err, "error stat the .git directory")
+		return nil, errors.Wrapf(err, "error detecting the version control system of %s", p)
 	}
 
-	pp := p
-	if !gitInfo.IsDir() {
-		gitC, err := ioutil.ReadFile(dotGit)
-		if err != nil {
-			return nil, errors.Wrap(err, "error reading the .git file")
-		}
-
-		sm := gitWorktreeRootRegex.FindSubmatch(bytes.Trim(gitC, "\n"))
-		if len(sm) != 2 {
-			return nil, ErrDotGitMalformed
-		}
-
-		pp = string(sm[1])
+	pp, err := v.RepositoryRoot(p)
+	if err != nil {
+		return nil, err
 	}
 
 	// clean the path
@@ -218,73 +352,18 @@ func (c *code) GetProjectByAbsolutePath(p string) (ifaces.Project, error) {
 	return c.GetProjectByRelativePath(pp)
 }
 
-func (c *code) RepositoriesDir() string { return path.Join(c.path, "repositories") }
-
-func (c *code) StoriesDir() string { return path.Join(c.path, "stories") }
-
-// scan scans the entire code directory to build the workspaces
-func (c *code) scan() {
-	// initialize the variables
-	var wg sync.WaitGroup
-	out := make(chan string, 1000)
-	// start the workers
-	wg.Add(1)
-	go c.scanWorker(&wg, out, "")
-	// start the reducer
-	reducerQuit := make(chan struct{})
-	go c.scanReducer(out, reducerQuit)
-	// wait for the workers to return
-	wg.Wait()
-	// ask the reducer to die
-	close(out)
-	<-reducerQuit
-}
-
-func (c *code) projectPath(importPath string) string {
-	return path.Join(c.RepositoriesDir(), importPath)
-}
-
-func (c *code) scanWorker(wg *sync.WaitGroup, out chan string, ipath string) {
-	defer wg.Done()
+func (c *code) RepositoriesDir() string { return path.Join(c.path, c.repositoriesDirname) }
 
-	// do we have a .git folder here?
-	if _, err := os.Stat(path.Join(c.projectPath(ipath), ".git")); err == nil {
-		log.Debug().Str("path", c.projectPath(ipath)).Msg("found a Git repository")
-		// return this import path
-		out <- ipath
+func (c *code) StoriesDir() string { return path.Join(c.path, c.storiesDirname) }
 
-		return
-	}
-
-	// scan the folder
-	entries, err := ioutil.ReadDir(c.projectPath(ipath))
-	if err != nil {
-		if os.IsNotExist(err) {
-			return
-		}
-		log.Fatal().Str("path", c.projectPath(ipath)).Msgf("error reading the directory: %s", err)
-	}
-	for _, entry := range entries {
-		// scan the entry if it's a directory
-		if entry.IsDir() {
-			wg.Add(1)
-			go c.scanWorker(wg, out, path.Join(ipath, entry.Name()))
-		}
-	}
+// scan scans the directories (relative to the repositories directory) and
+// records them, along with all projects found, in the index.
+func (c *code) scan(idx *index, dirs []string) error {
+	return newScanner(c, idx).run(dirs)
 }
 
-func (c *code) scanReducer(out chan string, quit chan struct{}) {
-	// iterate over the channel
-	for {
-		select {
-		case importPath, ok := <-out:
-			if !ok {
-				close(quit)
-				return
-			}
-			c.addProject(importPath)
-		}
-	}
+func (c *code) projectPath(importPath string) string {
+	return path.Join(c.RepositoriesDir(), importPath)
 }
 
 // addProject add the project by the import path. The project is not checked if
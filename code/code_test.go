@@ -10,6 +10,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/adrg/xdg"
 	"github.com/kalbasit/swm/ifaces"
 	"github.com/kalbasit/swm/story"
 	"github.com/kalbasit/swm/testhelper"
@@ -33,6 +34,10 @@ func TestScan(t *testing.T) {
 	// delete it once we are done here
 	defer func() { os.RemoveAll(dir) }()
 
+	// keep the index of the code within the temporary directory
+	xdg.CacheHome = dir
+	defer xdg.Reload()
+
 	// create the filesystem we want to scan
 	require.NoError(t, testhelper.CreateProjects(dir))
 
@@ -74,6 +79,10 @@ func TestGetProject(t *testing.T) {
 	// delete it once we are done here
 	defer func() { os.RemoveAll(dir) }()
 
+	// keep the index of the code within the temporary directory
+	xdg.CacheHome = dir
+	defer xdg.Reload()
+
 	// create the filesystem we want to scan
 	require.NoError(t, testhelper.CreateProjects(dir))
 
@@ -123,6 +132,10 @@ func TestProjects(t *testing.T) {
 	// delete it once we are done here
 	defer func() { os.RemoveAll(dir) }()
 
+	// keep the index of the code within the temporary directory
+	xdg.CacheHome = dir
+	defer xdg.Reload()
+
 	// create the filesystem we want to scan
 	require.NoError(t, testhelper.CreateProjects(dir))
 
@@ -156,13 +169,17 @@ func TestClone(t *testing.T) {
 		// delete it once we are done here
 		defer func() { os.RemoveAll(dir) }()
 
+		// keep the index of the code within the temporary directory
+		xdg.CacheHome = dir
+		defer xdg.Reload()
+
 		// create a code
 		c := New(dir, regexp.MustCompile("^.snapshots$"))
 		require.NoError(t, c.Scan())
 
 		// assert that we get an error
-		err = c.Clone("file://path/to/inexistent-repository")
-		assert.EqualError(t, err, "exit status 128")
+		err = c.Clone("file://path/to/inexistent-repository", "")
+		assert.Error(t, err)
 
 		// make sure that the parent of the repository does not exist
 		assert.NoDirExists(t, path.Join(c.RepositoriesDir(), "path"))
@@ -179,6 +196,10 @@ func TestClone(t *testing.T) {
 		// delete it once we are done here
 		defer func() { os.RemoveAll(dir) }()
 
+		// keep the index of the code within the temporary directory
+		xdg.CacheHome = dir
+		defer xdg.Reload()
+
 		// create the filesystem we want to scan
 		require.NoError(t, testhelper.CreateProjects(dir))
 
@@ -191,7 +212,7 @@ func TestClone(t *testing.T) {
 		_, err = c.GetProjectByRelativePath(importPath)
 		require.True(t, errors.Is(err, ErrProjectNotFound))
 
-		err = c.Clone(fmt.Sprintf("file://%s", path.Join(dir, ".snapshots", "github.com/owner4/repo4")))
+		err = c.Clone(fmt.Sprintf("file://%s", path.Join(dir, ".snapshots", "github.com/owner4/repo4")), "")
 		if assert.NoError(t, err) {
 			prj, err := c.GetProjectByRelativePath(importPath)
 			if assert.NoError(t, err) {
@@ -210,6 +231,10 @@ func TestGetProjectByAbsolutePath(t *testing.T) {
 	// delete it once we are done here
 	defer func() { os.RemoveAll(dir) }()
 
+	// keep the index of the code within the temporary directory
+	xdg.CacheHome = dir
+	defer xdg.Reload()
+
 	// create the filesystem we want to scan
 	require.NoError(t, testhelper.CreateProjects(dir))
 
@@ -235,3 +260,71 @@ func TestGetProjectByAbsolutePath(t *testing.T) {
 	_, err = c.GetProjectByAbsolutePath(dir + "/repositories/github.com/user/repo")
 	assert.Error(t, err)
 }
+
+func TestScanExclude(t *testing.T) {
+	// create a temporary directory
+	dir, err := ioutil.TempDir("", "swm-test-*")
+	require.NoError(t, err)
+
+	// delete it once we are done here
+	defer func() { os.RemoveAll(dir) }()
+
+	// keep the index of the code within the temporary directory
+	xdg.CacheHome = dir
+	defer xdg.Reload()
+
+	// create the filesystem we want to scan
+	require.NoError(t, testhelper.CreateProjects(dir))
+
+	c := New(dir, regexp.MustCompile(`^github\.com/owner2(/|$)`))
+	require.NoError(t, c.Scan())
+
+	assert.Equal(t, []string{"github.com/owner1/repo1", "github.com/owner3/repo3"}, importPaths(c.(*code)))
+
+	_, err = c.GetProjectByRelativePath("github.com/owner2/repo2")
+	assert.True(t, errors.Is(err, ErrProjectNotFound))
+
+	// changing the pattern must not load the projects from the index
+	c = New(dir, regexp.MustCompile(`repo3$`))
+	require.NoError(t, c.Scan())
+	assert.Equal(t, []string{"github.com/owner1/repo1", "github.com/owner2/repo2"}, importPaths(c.(*code)))
+}
+
+func TestDirnames(t *testing.T) {
+	// create a temporary directory
+	dir, err := ioutil.TempDir("", "swm-test-*")
+	require.NoError(t, err)
+
+	// delete it once we are done here
+	defer func() { os.RemoveAll(dir) }()
+
+	// keep the index of the code within the temporary directory
+	xdg.CacheHome = dir
+	defer xdg.Reload()
+
+	// create the filesystem we want to scan, and move the repositories
+	require.NoError(t, testhelper.CreateProjects(dir))
+	require.NoError(t, os.Rename(path.Join(dir, "repositories"), path.Join(dir, "repos")))
+
+	c := New(dir, nil, WithRepositoriesDirname("repos"), WithStoriesDirname("features"))
+	assert.Equal(t, path.Join(dir, "repos"), c.RepositoriesDir())
+	assert.Equal(t, path.Join(dir, "features"), c.StoriesDir())
+
+	require.NoError(t, c.Scan())
+	assert.Equal(t, []string{"github.com/owner1/repo1", "github.com/owner2/repo2", "github.com/owner3/repo3"}, importPaths(c.(*code)))
+
+	s, err := story.New(t.Name(), "")
+	require.NoError(t, err)
+
+	prj, err := c.GetProjectByRelativePath("github.com/owner1/repo1")
+	require.NoError(t, err)
+	assert.Equal(t, path.Join(dir, "repos", "github.com/owner1/repo1"), prj.Path(nil))
+	require.NoError(t, prj.CreateStory(s))
+	assert.DirExists(t, path.Join(dir, "features", t.Name(), "github.com/owner1/repo1"))
+
+	// the project of the story is found by its absolute path
+	prj, err = c.GetProjectByAbsolutePath(path.Join(dir, "features", t.Name(), "github.com/owner1/repo1"))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "github.com/owner1/repo1", prj.String())
+	}
+}
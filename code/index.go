@@ -0,0 +1,174 @@
+package code
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/adrg/xdg"
+	"github.com/pkg/errors"
+)
+
+// indexVersion is the version of the on-disk index format. Bump it whenever
+// the layout of the index changes, older indexes are discarded on load.
+const indexVersion = 1
+
+// dirStat holds the information of a directory that is needed to cheaply
+// validate if its content has changed since it was last scanned.
+type dirStat struct {
+	ModTime time.Time `json:"mod_time"`
+	Inode   uint64    `json:"inode"`
+}
+
+// index is the persistent project index of a code path. It records every
+// directory that was walked during the scan (relative to the repositories
+// directory) along with all the projects that were found.
+type index struct {
+	Version     int                `json:"version"`
+	Path        string             `json:"path"`
+	Fingerprint string             `json:"fingerprint"`
+	Directories map[string]dirStat `json:"directories"`
+	Projects    []string           `json:"projects"`
+}
+
+func newIndex(p, fingerprint string) *index {
+	return &index{
+		Version:     indexVersion,
+		Path:        p,
+		Fingerprint: fingerprint,
+		Directories: make(map[string]dirStat),
+	}
+}
+
+// indexPath returns the path of the index file for the code path p.
+func indexPath(p string) string {
+	name := strings.Replace(strings.TrimPrefix(p, string(os.PathSeparator)), string(os.PathSeparator), "_", -1)
+	return path.Join(xdg.CacheHome, "swm", "code", name+".json")
+}
+
+// loadIndex loads the index of the code path p. It returns nil without an
+// error if the index does not exist, was created with different settings or
+// is not usable.
+func loadIndex(p, fingerprint string) (*index, error) {
+	f, err := os.Open(indexPath(p))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "error opening the index file")
+	}
+	defer f.Close()
+
+	var idx index
+	if err := json.NewDecoder(f).Decode(&idx); err != nil {
+		// a corrupted index is not an error, it will be rebuilt
+		return nil, nil
+	}
+	if idx.Version != indexVersion || idx.Path != p || idx.Fingerprint != fingerprint || idx.Directories == nil {
+		return nil, nil
+	}
+
+	return &idx, nil
+}
+
+// save writes the index to disk atomically.
+func (idx *index) save() error {
+	ip := indexPath(idx.Path)
+	if err := os.MkdirAll(path.Dir(ip), 0755); err != nil {
+		return errors.Wrap(err, "error creating the parent directory of the index file")
+	}
+
+	sort.Strings(idx.Projects)
+
+	tmp := ip + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return errors.Wrap(err, "error opening the index file for writing")
+	}
+	if err := json.NewEncoder(f).Encode(idx); err != nil {
+		f.Close()
+		return errors.Wrap(err, "error encoding the index as JSON")
+	}
+	if err := f.Close(); err != nil {
+		return errors.Wrap(err, "error closing the index file")
+	}
+
+	return os.Rename(tmp, ip)
+}
+
+// staleDirectories returns the directories, relative to base, that have
+// changed since they were recorded in the index. A directory that is nested
+// inside another stale directory is not returned as it will be rescanned
+// along with its parent.
+func (idx *index) staleDirectories(base string) []string {
+	var stale []string
+	for dir, ds := range idx.Directories {
+		cur, err := statDir(path.Join(base, dir))
+		if err != nil || !cur.ModTime.Equal(ds.ModTime) || cur.Inode != ds.Inode {
+			stale = append(stale, dir)
+		}
+	}
+
+	// sort the directories so parents are always visited before their children
+	sort.Strings(stale)
+
+	var res []string
+stale:
+	for _, dir := range stale {
+		for _, parent := range res {
+			if isWithin(parent, dir) {
+				continue stale
+			}
+		}
+		res = append(res, dir)
+	}
+
+	return res
+}
+
+// forget removes every directory and project that lives within dir.
+func (idx *index) forget(dir string) {
+	for d := range idx.Directories {
+		if isWithin(dir, d) {
+			delete(idx.Directories, d)
+		}
+	}
+
+	var projects []string
+	for _, prj := range idx.Projects {
+		if !isWithin(dir, prj) {
+			projects = append(projects, prj)
+		}
+	}
+	idx.Projects = projects
+}
+
+// isWithin returns true if p is dir or is a descendant of dir. The empty dir
+// is the root of all paths.
+func isWithin(dir, p string) bool {
+	return dir == "" || p == dir || strings.HasPrefix(p, dir+"/")
+}
+
+// statDir returns the dirStat of the directory p.
+func statDir(p string) (dirStat, error) {
+	fi, err := os.Stat(p)
+	if err != nil {
+		return dirStat{}, err
+	}
+
+	return newDirStat(fi), nil
+}
+
+// newDirStat returns the dirStat of the file info.
+func newDirStat(fi os.FileInfo) dirStat {
+	ds := dirStat{ModTime: fi.ModTime()}
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		ds.Inode = uint64(st.Ino)
+	}
+
+	return ds
+}
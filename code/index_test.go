@@ -0,0 +1,165 @@
+package code
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"testing"
+
+	"github.com/adrg/xdg"
+	"github.com/kalbasit/swm/testhelper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func importPaths(c *code) []string {
+	var res []string
+	for _, prj := range c.Projects() {
+		res = append(res, prj.String())
+	}
+	sort.Strings(res)
+
+	return res
+}
+
+func TestScanIndex(t *testing.T) {
+	// create a temporary directory
+	dir, err := ioutil.TempDir("", "swm-test-*")
+	require.NoError(t, err)
+
+	// delete it once we are done here
+	defer func() { os.RemoveAll(dir) }()
+
+	// keep the index of the code within the temporary directory
+	xdg.CacheHome = dir
+	defer xdg.Reload()
+
+	// create the filesystem we want to scan
+	require.NoError(t, testhelper.CreateProjects(dir))
+
+	fp := New(dir, regexp.MustCompile("^.snapshots$")).(*code).fingerprint()
+	expected := []string{"github.com/owner1/repo1", "github.com/owner2/repo2", "github.com/owner3/repo3"}
+
+	t.Run("a full scan creates the index", func(t *testing.T) {
+		c := New(dir, regexp.MustCompile("^.snapshots$")).(*code)
+		require.NoError(t, c.Scan())
+		assert.Equal(t, expected, importPaths(c))
+
+		assert.FileExists(t, indexPath(dir))
+		idx, err := loadIndex(dir, fp)
+		require.NoError(t, err)
+		if assert.NotNil(t, idx) {
+			assert.Equal(t, expected, idx.Projects)
+			assert.Contains(t, idx.Directories, "")
+			assert.Contains(t, idx.Directories, "github.com")
+			assert.NotContains(t, idx.Directories, "github.com/owner1/repo1")
+		}
+	})
+
+	t.Run("an unchanged index is trusted", func(t *testing.T) {
+		idx, err := loadIndex(dir, fp)
+		require.NoError(t, err)
+		require.NotNil(t, idx)
+		idx.Projects = append(idx.Projects, "github.com/owner1/cached")
+		require.NoError(t, idx.save())
+
+		c := New(dir, regexp.MustCompile("^.snapshots$")).(*code)
+		require.NoError(t, c.Scan())
+		assert.Contains(t, importPaths(c), "github.com/owner1/cached")
+	})
+
+	t.Run("rebuild ignores the index", func(t *testing.T) {
+		c := New(dir, regexp.MustCompile("^.snapshots$")).(*code)
+		require.NoError(t, c.Rebuild())
+		assert.Equal(t, expected, importPaths(c))
+	})
+
+	t.Run("a read-only index is not saved", func(t *testing.T) {
+		before, err := ioutil.ReadFile(indexPath(dir))
+		require.NoError(t, err)
+		require.NoError(t, os.MkdirAll(path.Join(dir, "repositories", "github.com", "owner8", "repo8", ".git"), 0755))
+		defer os.RemoveAll(path.Join(dir, "repositories", "github.com", "owner8"))
+
+		c := New(dir, regexp.MustCompile("^.snapshots$"), WithReadOnlyIndex()).(*code)
+		require.NoError(t, c.Rebuild())
+		assert.Contains(t, importPaths(c), "github.com/owner8/repo8")
+
+		after, err := ioutil.ReadFile(indexPath(dir))
+		require.NoError(t, err)
+		assert.Equal(t, string(before), string(after))
+	})
+
+	t.Run("changed directories are rescanned", func(t *testing.T) {
+		require.NoError(t, os.MkdirAll(path.Join(dir, "repositories", "github.com", "owner7", "repo7", ".git"), 0755))
+		require.NoError(t, os.RemoveAll(path.Join(dir, "repositories", "github.com", "owner3")))
+
+		c := New(dir, regexp.MustCompile("^.snapshots$")).(*code)
+		require.NoError(t, c.Scan())
+		assert.Equal(t, []string{"github.com/owner1/repo1", "github.com/owner2/repo2", "github.com/owner7/repo7"}, importPaths(c))
+
+		idx, err := loadIndex(dir, fp)
+		require.NoError(t, err)
+		if assert.NotNil(t, idx) {
+			assert.Contains(t, idx.Directories, "github.com/owner7")
+			assert.NotContains(t, idx.Directories, "github.com/owner3")
+		}
+	})
+
+	t.Run("an index of a different code path is ignored", func(t *testing.T) {
+		idx, err := loadIndex(dir, fp)
+		require.NoError(t, err)
+		require.NotNil(t, idx)
+		idx.Path = "/some/other/path"
+		b, err := json.Marshal(idx)
+		require.NoError(t, err)
+		require.NoError(t, ioutil.WriteFile(indexPath(dir), b, 0644))
+
+		idx, err = loadIndex(dir, fp)
+		require.NoError(t, err)
+		assert.Nil(t, idx)
+	})
+
+	t.Run("an index created with different settings is ignored", func(t *testing.T) {
+		c := New(dir, nil).(*code)
+		require.NoError(t, c.Rebuild())
+
+		idx, err := loadIndex(dir, New(dir, nil, WithMaxDepth(2)).(*code).fingerprint())
+		require.NoError(t, err)
+		assert.Nil(t, idx)
+	})
+}
+
+func TestStaleDirectories(t *testing.T) {
+	// create a temporary directory
+	dir, err := ioutil.TempDir("", "swm-test-*")
+	require.NoError(t, err)
+
+	// delete it once we are done here
+	defer func() { os.RemoveAll(dir) }()
+
+	for _, d := range []string{"a", "a/b", "a-b", "c"} {
+		require.NoError(t, os.MkdirAll(path.Join(dir, d), 0755))
+	}
+
+	idx := newIndex(dir, "")
+	for _, d := range []string{"", "a", "a/b", "a-b", "c"} {
+		ds, err := statDir(path.Join(dir, d))
+		require.NoError(t, err)
+		idx.Directories[d] = ds
+	}
+
+	assert.Empty(t, idx.staleDirectories(dir))
+
+	// removing a/b changes a, and creating a-b/d changes a-b
+	require.NoError(t, os.Remove(path.Join(dir, "a", "b")))
+	require.NoError(t, os.Mkdir(path.Join(dir, "a-b", "d"), 0755))
+	assert.Equal(t, []string{"a", "a-b"}, idx.staleDirectories(dir))
+
+	idx.forget("a")
+	assert.NotContains(t, idx.Directories, "a")
+	assert.NotContains(t, idx.Directories, "a/b")
+	assert.Contains(t, idx.Directories, "a-b")
+}
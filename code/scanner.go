@@ -0,0 +1,271 @@
+package code
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/kalbasit/swm/vcs"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultSkipDirs is the list of directory names that are never descended
+// into while scanning for projects.
+var DefaultSkipDirs = []string{"node_modules", "vendor", ".direnv"}
+
+// readDir reads the entries of a directory, the tests replace it to fail.
+var readDir = ioutil.ReadDir
+
+// ScanError is returned by Scan and Rebuild if some directories could not be
+// scanned. The projects that were found are still loaded.
+type ScanError struct {
+	Errors []error
+}
+
+func (e *ScanError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		msgs = append(msgs, err.Error())
+	}
+
+	return fmt.Sprintf("%d errors occurred while scanning: %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+// scanTask is a directory that must be visited by the scanner.
+type scanTask struct {
+	// ipath is the path of the directory relative to the repositories directory
+	ipath string
+
+	// depth is the number of path elements of ipath
+	depth int
+}
+
+// fileID uniquely identifies a directory on the system, it's used to detect
+// loops, such as bind mounts of a parent directory.
+type fileID struct {
+	dev uint64
+	ino uint64
+}
+
+// scanner walks the repositories directory using a bounded pool of workers
+// and records all the directories and projects it finds in an index.
+type scanner struct {
+	root        string
+	parallelism int
+	maxDepth    int
+	skipDirs    map[string]struct{}
+	exclude     *regexp.Regexp
+
+	// queue of directories that are waiting to be visited
+	mu      sync.Mutex
+	cond    *sync.Cond
+	tasks   []scanTask
+	pending int
+
+	// the result of the scan
+	resultMu sync.Mutex
+	idx      *index
+	visited  map[fileID]struct{}
+	errs     []error
+}
+
+func newScanner(c *code, idx *index) *scanner {
+	s := &scanner{
+		root:        c.RepositoriesDir(),
+		parallelism: c.parallelism,
+		maxDepth:    c.maxDepth,
+		skipDirs:    make(map[string]struct{}),
+		exclude:     c.excludePattern,
+		idx:         idx,
+		visited:     make(map[fileID]struct{}),
+	}
+	s.cond = sync.NewCond(&s.mu)
+	if s.parallelism < 1 {
+		s.parallelism = 1
+	}
+	for _, name := range c.skipDirs {
+		s.skipDirs[name] = struct{}{}
+	}
+
+	return s
+}
+
+// run visits the directories (relative to the repositories directory) and
+// everything below them. It returns a *ScanError if any of the directories
+// could not be read.
+func (s *scanner) run(dirs []string) error {
+	for _, dir := range dirs {
+		depth := 0
+		if dir != "" {
+			depth = strings.Count(dir, "/") + 1
+		}
+		s.push(scanTask{ipath: dir, depth: depth})
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < s.parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				t, ok := s.pop()
+				if !ok {
+					return
+				}
+				s.visit(t)
+				s.done()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(s.errs) > 0 {
+		return &ScanError{Errors: s.errs}
+	}
+
+	return nil
+}
+
+// push adds a task to the queue.
+func (s *scanner) push(t scanTask) {
+	s.mu.Lock()
+	s.tasks = append(s.tasks, t)
+	s.pending++
+	s.mu.Unlock()
+	s.cond.Signal()
+}
+
+// pop returns the next task, blocking until one is available. It returns
+// false once all the tasks are done.
+func (s *scanner) pop() (scanTask, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for len(s.tasks) == 0 && s.pending > 0 {
+		s.cond.Wait()
+	}
+	if len(s.tasks) == 0 {
+		return scanTask{}, false
+	}
+
+	t := s.tasks[len(s.tasks)-1]
+	s.tasks = s.tasks[:len(s.tasks)-1]
+
+	return t, true
+}
+
+// done marks a task popped from the queue as done.
+func (s *scanner) done() {
+	s.mu.Lock()
+	s.pending--
+	finished := s.pending == 0
+	s.mu.Unlock()
+
+	if finished {
+		s.cond.Broadcast()
+	}
+}
+
+func (s *scanner) visit(t scanTask) {
+	p := path.Join(s.root, t.ipath)
+
+	if t.ipath != "" && s.exclude != nil && s.exclude.MatchString(t.ipath) {
+		log.Debug().Str("import-path", t.ipath).Msg("excluding the directory")
+		return
+	}
+
+	// is this directory a repository?
+	if v, err := vcs.Detect(p); err == nil {
+		log.Debug().Str("path", p).Str("vcs", v.Name()).Msg("found a repository")
+		s.addProject(t.ipath)
+		return
+	}
+
+	// stat the folder before reading it so any change happening while we are
+	// reading it is picked up by the next scan.
+	fi, err := os.Stat(p)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// record the directory so its creation is picked up by the next scan
+			s.addDirectory(t.ipath, dirStat{})
+			return
+		}
+		s.fail(t.ipath, errors.Wrapf(err, "error stat the directory %s", p))
+		return
+	}
+	if !s.markVisited(fi) {
+		log.Debug().Str("path", p).Msg("directory was already visited, not following the loop")
+		return
+	}
+
+	entries, err := readDir(p)
+	if err != nil {
+		s.fail(t.ipath, errors.Wrapf(err, "error reading the directory %s", p))
+		return
+	}
+	s.addDirectory(t.ipath, newDirStat(fi))
+
+	if s.maxDepth > 0 && t.depth >= s.maxDepth {
+		return
+	}
+	for _, entry := range entries {
+		if _, ok := s.skipDirs[entry.Name()]; ok {
+			continue
+		}
+
+		// the symlinks are not followed, a directory reachable through
+		// several paths would be indexed under whichever path is scanned
+		// first.
+		if entry.IsDir() {
+			s.push(scanTask{ipath: path.Join(t.ipath, entry.Name()), depth: t.depth + 1})
+		}
+	}
+}
+
+// markVisited records the directory as visited and returns false if it was
+// already visited.
+func (s *scanner) markVisited(fi os.FileInfo) bool {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return true
+	}
+	id := fileID{dev: uint64(st.Dev), ino: uint64(st.Ino)}
+
+	s.resultMu.Lock()
+	defer s.resultMu.Unlock()
+	if _, ok := s.visited[id]; ok {
+		return false
+	}
+	s.visited[id] = struct{}{}
+
+	return true
+}
+
+func (s *scanner) addProject(ipath string) {
+	s.resultMu.Lock()
+	s.idx.Projects = append(s.idx.Projects, ipath)
+	s.resultMu.Unlock()
+}
+
+func (s *scanner) addDirectory(ipath string, ds dirStat) {
+	s.resultMu.Lock()
+	s.idx.Directories[ipath] = ds
+	s.resultMu.Unlock()
+}
+
+// fail records the error, the directory is recorded with an empty stat so it
+// is scanned again on the next scan.
+func (s *scanner) fail(ipath string, err error) {
+	log.Debug().Err(err).Str("import-path", ipath).Msg("error scanning the directory")
+
+	s.resultMu.Lock()
+	s.idx.Directories[ipath] = dirStat{}
+	s.errs = append(s.errs, err)
+	s.resultMu.Unlock()
+}
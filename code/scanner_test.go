@@ -0,0 +1,96 @@
+package code
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/adrg/xdg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanner(t *testing.T) {
+	// create a temporary directory
+	dir, err := ioutil.TempDir("", "swm-test-*")
+	require.NoError(t, err)
+
+	// delete it once we are done here
+	defer func() { os.RemoveAll(dir) }()
+
+	// keep the index of the code within the temporary directory
+	xdg.CacheHome = dir
+	defer xdg.Reload()
+
+	rd := path.Join(dir, "repositories")
+	for _, p := range []string{
+		"github.com/owner1/repo1/.git",
+		"github.com/owner1/repo1/node_modules/dep/.git",
+		"github.com/owner2/node_modules/repo/.git",
+		"github.com/owner2/vendor/repo/.git",
+		"gitlab.com/group/subgroup/repo/.git",
+	} {
+		require.NoError(t, os.MkdirAll(path.Join(rd, p), 0755))
+	}
+
+	// create a symlink loop, it's not followed
+	require.NoError(t, os.Symlink(path.Join(rd, "github.com"), path.Join(rd, "github.com", "owner2", "loop")))
+
+	t.Run("skip directories and symlinks", func(t *testing.T) {
+		c := New(dir, nil, WithParallelism(2)).(*code)
+		require.NoError(t, c.Rebuild())
+		assert.Equal(t, []string{"github.com/owner1/repo1", "gitlab.com/group/subgroup/repo"}, importPaths(c))
+	})
+
+	t.Run("the aliases of a directory are not followed", func(t *testing.T) {
+		aliases := []string{path.Join(rd, "gitlab.com", "alias"), path.Join(rd, "a-alias.com")}
+		require.NoError(t, os.Symlink(path.Join(rd, "gitlab.com", "group"), aliases[0]))
+		require.NoError(t, os.Symlink(path.Join(rd, "gitlab.com", "group", "subgroup", "repo"), aliases[1]))
+		defer func() {
+			for _, alias := range aliases {
+				os.Remove(alias)
+			}
+		}()
+
+		// the projects are found under their real path, whatever the order of
+		// the scan
+		for i := 0; i < 5; i++ {
+			c := New(dir, nil, WithParallelism(4)).(*code)
+			require.NoError(t, c.Rebuild())
+			assert.Equal(t, []string{"github.com/owner1/repo1", "gitlab.com/group/subgroup/repo"}, importPaths(c))
+		}
+	})
+
+	t.Run("custom skip directories", func(t *testing.T) {
+		c := New(dir, nil, WithSkipDirs("vendor", "loop")).(*code)
+		require.NoError(t, c.Rebuild())
+		assert.Equal(t, []string{"github.com/owner1/repo1", "github.com/owner2/node_modules/repo", "gitlab.com/group/subgroup/repo"}, importPaths(c))
+	})
+
+	t.Run("max depth", func(t *testing.T) {
+		c := New(dir, nil, WithMaxDepth(3)).(*code)
+		require.NoError(t, c.Rebuild())
+		assert.Equal(t, []string{"github.com/owner1/repo1"}, importPaths(c))
+	})
+
+	t.Run("errors are collected", func(t *testing.T) {
+		unreadable := path.Join(rd, "github.com", "owner1")
+		readDir = func(p string) ([]os.FileInfo, error) {
+			if p == unreadable {
+				return nil, os.ErrPermission
+			}
+			return ioutil.ReadDir(p)
+		}
+		defer func() { readDir = ioutil.ReadDir }()
+
+		c := New(dir, nil).(*code)
+		err := c.Rebuild()
+		var scanErr *ScanError
+		if assert.True(t, errors.As(err, &scanErr)) {
+			assert.Len(t, scanErr.Errors, 1)
+		}
+		assert.Equal(t, []string{"gitlab.com/group/subgroup/repo"}, importPaths(c))
+	})
+}
@@ -0,0 +1,184 @@
+package history
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/adrg/xdg"
+	"github.com/pkg/errors"
+)
+
+// maxTotalCount is the sum of the counts of all the entries above which the
+// counts are aged, so old entries eventually fade away.
+const maxTotalCount = 1000
+
+// agingFactor is applied to the counts when the history is aged, entries
+// with a count dropping below one are forgotten.
+const agingFactor = 0.9
+
+var nowFn = time.Now
+
+// Entry records the visits of a project.
+type Entry struct {
+	Count      float64   `json:"count"`
+	LastAccess time.Time `json:"last_access"`
+}
+
+// frecency returns the score of the entry, the visit count weighted by the
+// age of the last access.
+func (e *Entry) frecency(now time.Time) float64 {
+	age := now.Sub(e.LastAccess)
+	switch {
+	case age < time.Hour:
+		return e.Count * 4
+	case age < 24*time.Hour:
+		return e.Count * 2
+	case age < 7*24*time.Hour:
+		return e.Count / 2
+	default:
+		return e.Count / 4
+	}
+}
+
+// History is the history of the visited projects, either global or of a
+// story.
+type History struct {
+	path    string
+	Entries map[string]*Entry `json:"entries"`
+}
+
+// Load returns the history of the story, the global history is returned if
+// storyName is empty. A missing history is empty.
+func Load(storyName string) (*History, error) {
+	h := &History{path: filePath(storyName), Entries: make(map[string]*Entry)}
+
+	f, err := os.Open(h.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return h, nil
+		}
+		return nil, errors.Wrap(err, "error opening the history file")
+	}
+	defer f.Close()
+
+	if err := json.NewDecoder(f).Decode(h); err != nil {
+		return nil, errors.Wrap(err, "error decoding the history")
+	}
+	if h.Entries == nil {
+		h.Entries = make(map[string]*Entry)
+	}
+
+	return h, nil
+}
+
+// Remove removes the history of the story.
+func Remove(storyName string) error {
+	if err := os.Remove(filePath(storyName)); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "error removing the history file")
+	}
+
+	return nil
+}
+
+// filePath returns the path of the history file of the story, the history of
+// the stories lives outside of the stories directory of the data home so it's
+// not mistaken for a story.
+func filePath(storyName string) string {
+	if storyName == "" {
+		return path.Join(xdg.DataHome, "swm", "history", "global.json")
+	}
+
+	return path.Join(xdg.DataHome, "swm", "history", "stories", storyName+".json")
+}
+
+// Record records a visit of the project.
+func (h *History) Record(importPath string) {
+	e, ok := h.Entries[importPath]
+	if !ok {
+		e = &Entry{}
+		h.Entries[importPath] = e
+	}
+	e.Count++
+	e.LastAccess = nowFn()
+
+	h.age()
+}
+
+// age scales down the counts once their sum grows too large.
+func (h *History) age() {
+	var total float64
+	for _, e := range h.Entries {
+		total += e.Count
+	}
+	if total <= maxTotalCount {
+		return
+	}
+
+	for importPath, e := range h.Entries {
+		e.Count *= agingFactor
+		if e.Count < 1 {
+			delete(h.Entries, importPath)
+		}
+	}
+}
+
+// Score returns the frecency of the project, zero if it was never visited.
+func (h *History) Score(importPath string) float64 {
+	e, ok := h.Entries[importPath]
+	if !ok {
+		return 0
+	}
+
+	return e.frecency(nowFn())
+}
+
+// Save writes the history to disk.
+func (h *History) Save() error {
+	if err := os.MkdirAll(path.Dir(h.path), 0755); err != nil {
+		return errors.Wrap(err, "error creating the parent directory of the history file")
+	}
+
+	tmp := h.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return errors.Wrap(err, "error opening the history file for writing")
+	}
+	if err := json.NewEncoder(f).Encode(h); err != nil {
+		f.Close()
+		return errors.Wrap(err, "error encoding the history as JSON")
+	}
+	if err := f.Close(); err != nil {
+		return errors.Wrap(err, "error closing the history file")
+	}
+
+	return os.Rename(tmp, h.path)
+}
+
+// Rank sorts the import paths by their frecency in the histories, the first
+// history takes precedence and the following ones break the ties. Import
+// paths with the same scores keep their order.
+func Rank(importPaths []string, histories ...*History) []string {
+	scores := make(map[string][]float64, len(importPaths))
+	for _, ip := range importPaths {
+		for _, h := range histories {
+			scores[ip] = append(scores[ip], h.Score(ip))
+		}
+	}
+
+	res := make([]string, len(importPaths))
+	copy(res, importPaths)
+	sort.SliceStable(res, func(i, j int) bool {
+		si, sj := scores[res[i]], scores[res[j]]
+		for k := range si {
+			if si[k] != sj[k] {
+				return si[k] > sj[k]
+			}
+		}
+		return false
+	})
+
+	return res
+}
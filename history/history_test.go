@@ -0,0 +1,97 @@
+package history
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/adrg/xdg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistory(t *testing.T) {
+	// create a temporary directory
+	dir, err := ioutil.TempDir("", "swm-test-*")
+	require.NoError(t, err)
+
+	// delete it once we are done here
+	defer func() { os.RemoveAll(dir) }()
+
+	// keep the history within the temporary directory
+	xdg.DataHome = dir
+	defer xdg.Reload()
+
+	now := time.Now()
+	nowFn = func() time.Time { return now }
+	defer func() { nowFn = time.Now }()
+
+	t.Run("a missing history is empty", func(t *testing.T) {
+		h, err := Load("STORY-123")
+		require.NoError(t, err)
+		assert.Empty(t, h.Entries)
+	})
+
+	t.Run("the visits are persisted", func(t *testing.T) {
+		h, err := Load("STORY-123")
+		require.NoError(t, err)
+		h.Record("github.com/owner1/repo1")
+		h.Record("github.com/owner1/repo1")
+		require.NoError(t, h.Save())
+
+		h, err = Load("STORY-123")
+		require.NoError(t, err)
+		if assert.Contains(t, h.Entries, "github.com/owner1/repo1") {
+			assert.Equal(t, float64(2), h.Entries["github.com/owner1/repo1"].Count)
+		}
+
+		// the global history is separate
+		g, err := Load("")
+		require.NoError(t, err)
+		assert.Empty(t, g.Entries)
+
+		require.NoError(t, Remove("STORY-123"))
+		h, err = Load("STORY-123")
+		require.NoError(t, err)
+		assert.Empty(t, h.Entries)
+	})
+
+	t.Run("recent visits score higher", func(t *testing.T) {
+		h := &History{Entries: map[string]*Entry{
+			"old":    {Count: 10, LastAccess: now.Add(-30 * 24 * time.Hour)},
+			"recent": {Count: 2, LastAccess: now.Add(-time.Minute)},
+		}}
+		assert.Greater(t, h.Score("recent"), h.Score("old"))
+		assert.Zero(t, h.Score("never"))
+	})
+
+	t.Run("old entries fade away", func(t *testing.T) {
+		h := &History{Entries: map[string]*Entry{
+			"busy":   {Count: maxTotalCount, LastAccess: now},
+			"barely": {Count: 1, LastAccess: now},
+		}}
+		h.Record("busy")
+		assert.NotContains(t, h.Entries, "barely")
+		assert.Less(t, h.Entries["busy"].Count, float64(maxTotalCount))
+	})
+}
+
+func TestRank(t *testing.T) {
+	now := time.Now()
+	nowFn = func() time.Time { return now }
+	defer func() { nowFn = time.Now }()
+
+	s := &History{Entries: map[string]*Entry{
+		"b": {Count: 1, LastAccess: now},
+	}}
+	g := &History{Entries: map[string]*Entry{
+		"a": {Count: 1, LastAccess: now},
+		"b": {Count: 1, LastAccess: now},
+		"c": {Count: 5, LastAccess: now},
+	}}
+
+	assert.Equal(t, []string{"b", "c", "a", "d"}, Rank([]string{"a", "b", "c", "d"}, s, g))
+	assert.Equal(t, []string{"c", "a", "b", "d"}, Rank([]string{"a", "b", "c", "d"}, g))
+	assert.Equal(t, []string{"a", "b"}, Rank([]string{"a", "b"}))
+}
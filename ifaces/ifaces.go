@@ -23,9 +23,10 @@ type Code interface {
 	// GetProjectByRelativePath returns a project identified by it's relative path to the repositories directory.
 	GetProjectByRelativePath(string) (Project, error)
 
-	// Clone clones url as the new project. Will automatically compute the import
-	// path from the given URL.
-	Clone(url string) error
+	// Clone clones url as the new project using the named version control
+	// system, the default one is used if vcsName is empty. Will automatically
+	// compute the import path from the given URL.
+	Clone(url, vcsName string) error
 
 	// GetProjectByAbsolutePath returns the project corresponding to the absolute
 	// path.
@@ -34,6 +35,9 @@ type Code interface {
 	// Scan scans the code path.
 	Scan() error
 
+	// Rebuild ignores the cache and scans the entire code path.
+	Rebuild() error
+
 	// RepositoriesDir returns the absolute path to the repositories directory.
 	RepositoriesDir() string
 
@@ -60,6 +64,45 @@ type Project interface {
 
 	// Code returns the code this project is attached to
 	Code() Code
+
+	// RemoveStory removes the story path for this project.
+	RemoveStory(s Story) error
+
+	// VCS returns the version control system of this project.
+	VCS() (VCS, error)
+}
+
+// VCS defines the interface of a version control system
+type VCS interface {
+	// Name returns the name of the version control system
+	Name() string
+
+	// Detect returns true if the directory is a repository (or a workspace)
+	// managed by this version control system.
+	Detect(p string) bool
+
+	// RepositoryRoot returns the path of the repository the workspace at p
+	// belongs to. The path of a repository is its own root.
+	RepositoryRoot(p string) (string, error)
+
+	// Clone clones the url into the directory p.
+	Clone(url, p string) error
+
+	// CreateWorkspace creates a workspace of the repository at
+	// repositoryPath in workspacePath using the given branch. The branch is
+	// created, or reset, at the current revision of the repository.
+	CreateWorkspace(repositoryPath, workspacePath, branch string) error
+
+	// RemoveWorkspace removes the workspace at workspacePath from the
+	// repository at repositoryPath.
+	RemoveWorkspace(repositoryPath, workspacePath string) error
+
+	// Branches returns the branches of the repository at repositoryPath.
+	Branches(repositoryPath string) ([]string, error)
+
+	// Status returns one line per changed or untracked file of the
+	// repository (or the workspace) at p, it's empty if p is clean.
+	Status(p string) ([]string, error)
 }
 
 // Story defines the story interface
@@ -86,3 +129,47 @@ type Story interface {
 	// Remove saves the story in the data directory.
 	Remove() error
 }
+
+// Selector defines the interface of an interactive picker
+type Selector interface {
+	// Select lets the user pick one of the items and returns it.
+	Select(items []string) (string, error)
+}
+
+// Multiplexer defines the interface of a terminal multiplexer running a
+// session per project of a story
+type Multiplexer interface {
+	// Name returns the name of the multiplexer
+	Name() string
+
+	// SessionName returns the name of the session of the project.
+	SessionName(p Project) string
+
+	// EnsureSession starts the session of the project, unless it's already
+	// running, and returns its name.
+	EnsureSession(p Project) (string, error)
+
+	// Attach attaches the terminal to the session, or switches the client to
+	// it from within the multiplexer. The current pane is killed once the
+	// client switched if killPane is true.
+	Attach(sessionName string, killPane bool) error
+
+	// SetEnv sets the variables, formatted as KEY=value, in the environment
+	// of the session.
+	SetEnv(sessionName string, env []string) error
+
+	// Sessions returns the names of the running sessions of the story.
+	Sessions() ([]string, error)
+
+	// Kill kills the sessions of the story. The protected processes are
+	// asked to exit first if graceful is true, otherwise the sessions are not
+	// killed if one is running.
+	Kill(graceful bool) error
+
+	// SendKeys sends the keys, either key names (Enter, Escape, C-c) or
+	// text, to the session or to one of its panes.
+	SendKeys(target string, keys ...string) error
+
+	// Close disconnects from the multiplexer.
+	Close() error
+}
@@ -0,0 +1,272 @@
+package layout
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/bmatcuk/doublestar"
+	"github.com/pkg/errors"
+)
+
+// DefaultName is the name of the layout used when no rule matches the
+// project, and no default was configured.
+const DefaultName = "default"
+
+// The directions a pane is split from the previous pane of the window.
+const (
+	SplitHorizontal = "horizontal"
+	SplitVertical   = "vertical"
+)
+
+var (
+	// ErrUnknownLayout is returned if a layout is referenced but not defined.
+	ErrUnknownLayout = errors.New("unknown layout")
+
+	// ErrInvalidLayout is returned if a layout cannot be applied.
+	ErrInvalidLayout = errors.New("invalid layout")
+)
+
+// Pane is a pane split from the previous pane of the window.
+type Pane struct {
+	// Dir is the working directory of the pane, relative to the project. It
+	// defaults to the directory of the window.
+	Dir string `mapstructure:"dir"`
+
+	// Command is typed in the shell of the pane once created.
+	Command string `mapstructure:"command"`
+
+	// Split is the direction of the split, it defaults to vertical (the new
+	// pane is below the previous one).
+	Split string `mapstructure:"split"`
+
+	// Size is the size of the pane, in lines or columns, or a percentage
+	// of the window (30%).
+	Size string `mapstructure:"size"`
+
+	// Focus selects this pane in the window.
+	Focus bool `mapstructure:"focus"`
+}
+
+// Window is a window of the session, its first pane is described by the
+// window itself and the other panes are split from it.
+type Window struct {
+	Name    string `mapstructure:"name"`
+	Dir     string `mapstructure:"dir"`
+	Command string `mapstructure:"command"`
+	Focus   bool   `mapstructure:"focus"`
+	Panes   []Pane `mapstructure:"panes"`
+}
+
+// Layout describes the windows created with a new session.
+type Layout struct {
+	Windows []Window `mapstructure:"windows"`
+}
+
+// Default returns the builtin layout, an editor on the first window and a
+// shell on the second one.
+func Default() Layout {
+	return Layout{
+		Windows: []Window{
+			{Command: "type vim_ready &>/dev/null && vim_ready; clear; vim"},
+			{Focus: true},
+		},
+	}
+}
+
+// Validate returns an error if the layout cannot be applied.
+func (l Layout) Validate() error {
+	if len(l.Windows) == 0 {
+		return errors.Wrap(ErrInvalidLayout, "the layout has no windows")
+	}
+
+	var focused int
+	for i, w := range l.Windows {
+		if w.Focus {
+			focused++
+		}
+
+		var panesFocused int
+		for j, p := range w.Panes {
+			if p.Focus {
+				panesFocused++
+			}
+			switch p.Split {
+			case "", SplitHorizontal, SplitVertical:
+			default:
+				return errors.Wrapf(ErrInvalidLayout, "pane %d of window %d: the split must be %s or %s, not %q", j, i, SplitHorizontal, SplitVertical, p.Split)
+			}
+			if err := validateSize(p.Size); err != nil {
+				return errors.Wrapf(err, "pane %d of window %d", j, i)
+			}
+		}
+		if panesFocused > 1 {
+			return errors.Wrapf(ErrInvalidLayout, "window %d has more than one focused pane", i)
+		}
+	}
+	if focused > 1 {
+		return errors.Wrap(ErrInvalidLayout, "more than one window is focused")
+	}
+
+	return nil
+}
+
+// Flatten returns the layout with a single window, the other windows are
+// split from the first one as panes. The focused window becomes the focused
+// pane, or its focused pane if it has one.
+func (l Layout) Flatten() Layout {
+	if len(l.Windows) <= 1 {
+		return l
+	}
+
+	focused := 0
+	for i, w := range l.Windows {
+		if w.Focus {
+			focused = i
+		}
+	}
+
+	res := l.Windows[0]
+	res.Focus = false
+	res.Panes = nil
+	// focus is the pane to focus, -1 for the first pane of the window
+	focus := -1
+	for i, w := range l.Windows {
+		if i > 0 {
+			if i == focused {
+				focus = len(res.Panes)
+			}
+			res.Panes = append(res.Panes, Pane{Dir: w.Dir, Command: w.Command})
+		}
+		for _, p := range w.Panes {
+			if p.Focus && i == focused {
+				focus = len(res.Panes)
+			}
+			// the panes default to the directory of their window
+			if p.Dir == "" {
+				p.Dir = w.Dir
+			}
+			p.Focus = false
+			res.Panes = append(res.Panes, p)
+		}
+	}
+	if focus >= 0 {
+		res.Panes[focus].Focus = true
+	}
+
+	return Layout{Windows: []Window{res}}
+}
+
+func validateSize(size string) error {
+	if size == "" {
+		return nil
+	}
+
+	n, err := strconv.Atoi(strings.TrimSuffix(size, "%"))
+	if err != nil || n <= 0 || (strings.HasSuffix(size, "%") && n > 100) {
+		return errors.Wrapf(ErrInvalidLayout, "the size must be a number of cells or a percentage, not %q", size)
+	}
+
+	return nil
+}
+
+// Rule selects the layout of the projects with an import path matching a
+// glob pattern, for instance github.com/owner/* or github.com/**.
+type Rule struct {
+	Match  string `mapstructure:"match"`
+	Layout string `mapstructure:"layout"`
+}
+
+// Config holds the layouts and the rules selecting them.
+type Config struct {
+	// Default is the name of the layout used if no rule matches.
+	Default string
+
+	Layouts map[string]Layout
+
+	// Rules are tried in order, the first one matching is used. A rule
+	// matching the import path exactly takes precedence over the patterns.
+	Rules []Rule
+}
+
+// For returns the layout of the project with the given import path.
+func (c *Config) For(importPath string) (Layout, error) {
+	name, err := c.nameFor(importPath)
+	if err != nil {
+		return Layout{}, err
+	}
+
+	return c.Get(name)
+}
+
+// HasProjectRule returns true if a rule matches the import path exactly,
+// such a rule takes precedence over the layout preferred by the project.
+func (c *Config) HasProjectRule(importPath string) bool {
+	for _, r := range c.Rules {
+		if r.Match == importPath {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *Config) nameFor(importPath string) (string, error) {
+	for _, r := range c.Rules {
+		if r.Match == importPath {
+			return r.Layout, nil
+		}
+	}
+	for _, r := range c.Rules {
+		ok, err := doublestar.Match(r.Match, importPath)
+		if err != nil {
+			return "", errors.Wrapf(err, "error matching the pattern %q", r.Match)
+		}
+		if ok {
+			return r.Layout, nil
+		}
+	}
+	if c.Default != "" {
+		return c.Default, nil
+	}
+
+	return DefaultName, nil
+}
+
+// Get returns the layout with the given name, the builtin layout is named
+// default unless the config redefines it.
+func (c *Config) Get(name string) (Layout, error) {
+	if l, ok := c.Layouts[name]; ok {
+		return l, nil
+	}
+	if name == DefaultName {
+		return Default(), nil
+	}
+
+	return Layout{}, errors.Wrapf(ErrUnknownLayout, "%q", name)
+}
+
+// Validate returns an error if any of the layouts is invalid, or if the
+// default or a rule references an unknown layout.
+func (c *Config) Validate() error {
+	for name, l := range c.Layouts {
+		if err := l.Validate(); err != nil {
+			return errors.Wrapf(err, "layout %q", name)
+		}
+	}
+	if c.Default != "" {
+		if _, err := c.Get(c.Default); err != nil {
+			return errors.Wrap(err, "the default layout")
+		}
+	}
+	for i, r := range c.Rules {
+		// matching the pattern against itself reports the malformed patterns
+		if _, err := doublestar.Match(r.Match, r.Match); err != nil {
+			return errors.Wrapf(err, "rule %d: error parsing the pattern %q", i, r.Match)
+		}
+		if _, err := c.Get(r.Layout); err != nil {
+			return errors.Wrapf(err, "rule %d", i)
+		}
+	}
+
+	return nil
+}
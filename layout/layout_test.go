@@ -0,0 +1,120 @@
+package layout
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFor(t *testing.T) {
+	goService := Layout{Windows: []Window{{Command: "vim"}, {Name: "test", Command: "make watch"}}}
+	frontend := Layout{Windows: []Window{{Panes: []Pane{{Command: "yarn start", Split: SplitHorizontal, Size: "30%"}}}}}
+	c := &Config{
+		Layouts: map[string]Layout{"go-service": goService, "frontend": frontend},
+		Rules: []Rule{
+			{Match: "github.com/owner1/**", Layout: "go-service"},
+			{Match: "github.com/owner1/web", Layout: "frontend"},
+			{Match: "*.com/owner2/*", Layout: "frontend"},
+		},
+	}
+
+	for importPath, want := range map[string]Layout{
+		"github.com/owner1/api":  goService,
+		"github.com/owner1/web":  frontend,
+		"gitlab.com/owner2/web":  frontend,
+		"github.com/owner3/repo": Default(),
+	} {
+		t.Run(importPath, func(t *testing.T) {
+			l, err := c.For(importPath)
+			require.NoError(t, err)
+			assert.Equal(t, want, l)
+		})
+	}
+
+	t.Run("the configured default", func(t *testing.T) {
+		c := &Config{Default: "go-service", Layouts: map[string]Layout{"go-service": goService}}
+		l, err := c.For("github.com/owner3/repo")
+		require.NoError(t, err)
+		assert.Equal(t, goService, l)
+	})
+
+	t.Run("the default layout can be redefined", func(t *testing.T) {
+		c := &Config{Layouts: map[string]Layout{DefaultName: goService}}
+		l, err := c.For("github.com/owner3/repo")
+		require.NoError(t, err)
+		assert.Equal(t, goService, l)
+	})
+
+	t.Run("an unknown layout", func(t *testing.T) {
+		c := &Config{Default: "unknown"}
+		_, err := c.For("github.com/owner3/repo")
+		assert.True(t, errors.Is(err, ErrUnknownLayout))
+	})
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Default().Validate())
+
+	for name, l := range map[string]Layout{
+		"no windows":             {},
+		"two focused windows":    {Windows: []Window{{Focus: true}, {Focus: true}}},
+		"two focused panes":      {Windows: []Window{{Panes: []Pane{{Focus: true}, {Focus: true}}}}},
+		"an unknown split":       {Windows: []Window{{Panes: []Pane{{Split: "diagonal"}}}}},
+		"a malformed size":       {Windows: []Window{{Panes: []Pane{{Size: "a third"}}}}},
+		"a percentage above 100": {Windows: []Window{{Panes: []Pane{{Size: "150%"}}}}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.True(t, errors.Is(l.Validate(), ErrInvalidLayout))
+		})
+	}
+
+	t.Run("a rule with an unknown layout", func(t *testing.T) {
+		c := &Config{Rules: []Rule{{Match: "github.com/**", Layout: "unknown"}}}
+		assert.True(t, errors.Is(c.Validate(), ErrUnknownLayout))
+	})
+
+	t.Run("a malformed pattern", func(t *testing.T) {
+		c := &Config{Rules: []Rule{{Match: "github.com/[", Layout: DefaultName}}}
+		assert.Error(t, c.Validate())
+	})
+}
+
+func TestFlatten(t *testing.T) {
+	t.Run("the default layout", func(t *testing.T) {
+		l := Default().Flatten()
+		require.NoError(t, l.Validate())
+		assert.Equal(t, Layout{Windows: []Window{{
+			Command: Default().Windows[0].Command,
+			Panes:   []Pane{{Focus: true}},
+		}}}, l)
+	})
+
+	t.Run("a single window", func(t *testing.T) {
+		l := Layout{Windows: []Window{{Name: "editor", Panes: []Pane{{Command: "make watch"}}}}}
+		assert.Equal(t, l, l.Flatten())
+	})
+
+	t.Run("the focused pane of the focused window", func(t *testing.T) {
+		l := Layout{Windows: []Window{
+			{Name: "editor", Command: "vim", Panes: []Pane{{Command: "make watch", Focus: true}}},
+			{Name: "web", Dir: "web", Command: "yarn start", Focus: true, Panes: []Pane{{Command: "yarn test", Focus: true}, {Dir: "api"}}},
+		}}
+		assert.Equal(t, Layout{Windows: []Window{{
+			Name:    "editor",
+			Command: "vim",
+			Panes: []Pane{
+				{Command: "make watch"},
+				{Dir: "web", Command: "yarn start"},
+				{Dir: "web", Command: "yarn test", Focus: true},
+				{Dir: "api"},
+			},
+		}}}, l.Flatten())
+	})
+
+	t.Run("the first window is focused", func(t *testing.T) {
+		l := Layout{Windows: []Window{{Command: "vim"}, {Command: "make watch"}}}
+		assert.Equal(t, Layout{Windows: []Window{{Command: "vim", Panes: []Pane{{Command: "make watch"}}}}}, l.Flatten())
+	})
+}
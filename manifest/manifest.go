@@ -0,0 +1,201 @@
+package manifest
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/kalbasit/swm/layout"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// FileName is the name of the manifest at the root of a repository.
+const FileName = ".swm.yaml"
+
+// ErrInvalidManifest is returned if the manifest cannot be used.
+var ErrInvalidManifest = errors.New("invalid manifest")
+
+// Manifest describes how a repository is worked on, it's read from the
+// .swm.yaml file at the root of the repository.
+//
+//	layout: go-service
+//	setup:
+//	  - go mod download
+//	env:
+//	  - GOFLAGS=-mod=vendor
+//	copy:
+//	  - .env
+//	  - config/*.local.yaml
+//
+// The layout is either the name of a layout of the config file or a layout
+// with its windows.
+type Manifest struct {
+	// LayoutName is the name of the preferred layout.
+	LayoutName string
+
+	// Layout is the preferred layout, if defined by the manifest itself.
+	Layout *layout.Layout
+
+	// Setup are the shell commands run once a story is created, within the
+	// story.
+	Setup []string
+
+	// Env are the variables, formatted as KEY=value, exported into the
+	// session and the setup commands.
+	Env []string
+
+	// Copy are the glob patterns of the files copied from the repository
+	// into the new stories, such as the untracked .env file.
+	Copy []string
+
+	// Repository is the absolute path of the repository of the manifest, the
+	// trust of the manifest is bound to it. It's the directory the manifest
+	// was loaded from, the repository of a story must be set by the caller.
+	Repository string
+
+	// raw is the content of the manifest file
+	raw []byte
+}
+
+// Load returns the manifest of the repository (or the story) at dir. A
+// missing manifest is empty.
+func Load(dir string) (*Manifest, error) {
+	m := &Manifest{}
+
+	p := path.Join(dir, FileName)
+	if _, err := os.Stat(p); err != nil {
+		if os.IsNotExist(err) {
+			return m, nil
+		}
+		return nil, errors.Wrap(err, "error reading the manifest")
+	}
+
+	raw, err := ioutil.ReadFile(p)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading the manifest %s", p)
+	}
+	if m.Repository, err = filepath.Abs(dir); err != nil {
+		return nil, err
+	}
+	m.raw = raw
+
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(bytes.NewReader(raw)); err != nil {
+		return nil, errors.Wrapf(err, "error reading the manifest %s", p)
+	}
+
+	if v.IsSet("layout.windows") {
+		m.Layout = &layout.Layout{}
+		if err := v.UnmarshalKey("layout", m.Layout); err != nil {
+			return nil, errors.Wrapf(err, "error reading the layout of the manifest %s", p)
+		}
+		if err := m.Layout.Validate(); err != nil {
+			return nil, errors.Wrapf(err, "error validating the layout of the manifest %s", p)
+		}
+	} else {
+		m.LayoutName = v.GetString("layout")
+	}
+	m.Setup = v.GetStringSlice("setup")
+	m.Env = v.GetStringSlice("env")
+	m.Copy = v.GetStringSlice("copy")
+
+	for _, e := range m.Env {
+		if !strings.Contains(e, "=") || strings.HasPrefix(e, "=") {
+			return nil, errors.Wrapf(ErrInvalidManifest, "%s: the environment variable %q is not formatted as KEY=value", p, e)
+		}
+	}
+	for _, pattern := range m.Copy {
+		if c := path.Clean(pattern); path.IsAbs(c) || c == ".." || strings.HasPrefix(c, "../") {
+			return nil, errors.Wrapf(ErrInvalidManifest, "%s: the files to copy %q are not within the repository", p, pattern)
+		}
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, errors.Wrapf(ErrInvalidManifest, "%s: error parsing the pattern %q: %s", p, pattern, err)
+		}
+	}
+
+	return m, nil
+}
+
+// CopyFiles copies the files matching the copy patterns of the manifest from
+// the repository at src to the story at dst. The files existing in the story
+// are kept as is.
+func (m *Manifest) CopyFiles(src, dst string) error {
+	for _, pattern := range m.Copy {
+		matches, err := filepath.Glob(path.Join(src, pattern))
+		if err != nil {
+			return errors.Wrapf(err, "error matching the pattern %q", pattern)
+		}
+		for _, match := range matches {
+			rel, err := filepath.Rel(src, match)
+			if err != nil {
+				return err
+			}
+			if err := copyTree(match, path.Join(dst, rel)); err != nil {
+				return errors.Wrapf(err, "error copying %s", rel)
+			}
+		}
+	}
+
+	return nil
+}
+
+// copyTree copies the file or the directory src to dst.
+func copyTree(src, dst string) error {
+	return filepath.Walk(src, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		target := path.Join(dst, rel)
+
+		switch {
+		case fi.Name() == ".git":
+			// never copy the metadata of the repository
+			if fi.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		case fi.IsDir():
+			return os.MkdirAll(target, fi.Mode().Perm())
+		case !fi.Mode().IsRegular():
+			// symlinks and special files are not copied
+			return nil
+		}
+		if _, err := os.Lstat(target); err == nil {
+			return nil
+		}
+		if err := os.MkdirAll(path.Dir(target), 0755); err != nil {
+			return err
+		}
+
+		return copyFile(p, target, fi.Mode().Perm())
+	})
+}
+
+func copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
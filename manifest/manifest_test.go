@@ -0,0 +1,199 @@
+package manifest
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/adrg/xdg"
+	"github.com/kalbasit/swm/layout"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeManifest(t *testing.T, dir, content string) {
+	require.NoError(t, ioutil.WriteFile(path.Join(dir, FileName), []byte(content), 0644))
+}
+
+func TestLoad(t *testing.T) {
+	// create a temporary directory
+	dir, err := ioutil.TempDir("", "swm-test-*")
+	require.NoError(t, err)
+
+	// delete it once we are done here
+	defer func() { os.RemoveAll(dir) }()
+
+	t.Run("a missing manifest is empty", func(t *testing.T) {
+		m, err := Load(dir)
+		require.NoError(t, err)
+		assert.Equal(t, &Manifest{}, m)
+	})
+
+	t.Run("a named layout", func(t *testing.T) {
+		writeManifest(t, dir, `
+layout: go-service
+setup:
+  - go mod download
+env:
+  - GOFLAGS=-mod=vendor
+copy:
+  - .env
+`)
+		m, err := Load(dir)
+		require.NoError(t, err)
+		assert.Equal(t, "go-service", m.LayoutName)
+		assert.Nil(t, m.Layout)
+		assert.Equal(t, []string{"go mod download"}, m.Setup)
+		assert.Equal(t, []string{"GOFLAGS=-mod=vendor"}, m.Env)
+		assert.Equal(t, []string{".env"}, m.Copy)
+		assert.Equal(t, dir, m.Repository)
+	})
+
+	t.Run("an inline layout", func(t *testing.T) {
+		writeManifest(t, dir, `
+layout:
+  windows:
+    - name: editor
+      command: vim
+      panes:
+        - command: yarn start
+          split: horizontal
+`)
+		m, err := Load(dir)
+		require.NoError(t, err)
+		assert.Empty(t, m.LayoutName)
+		assert.Equal(t, &layout.Layout{Windows: []layout.Window{{
+			Name:    "editor",
+			Command: "vim",
+			Panes:   []layout.Pane{{Command: "yarn start", Split: layout.SplitHorizontal}},
+		}}}, m.Layout)
+	})
+
+	t.Run("an invalid inline layout", func(t *testing.T) {
+		writeManifest(t, dir, `
+layout:
+  windows:
+    - panes:
+        - split: diagonal
+`)
+		_, err := Load(dir)
+		assert.True(t, errors.Is(err, layout.ErrInvalidLayout))
+	})
+
+	t.Run("a malformed environment variable", func(t *testing.T) {
+		writeManifest(t, dir, "env: [GOFLAGS]")
+		_, err := Load(dir)
+		assert.True(t, errors.Is(err, ErrInvalidManifest))
+	})
+
+	for _, pattern := range []string{"/etc/passwd", "../.env", "a/../../.env"} {
+		t.Run("copying "+pattern, func(t *testing.T) {
+			writeManifest(t, dir, "copy: ["+pattern+"]")
+			_, err := Load(dir)
+			assert.True(t, errors.Is(err, ErrInvalidManifest))
+		})
+	}
+}
+
+func TestCopyFiles(t *testing.T) {
+	// create a temporary directory
+	dir, err := ioutil.TempDir("", "swm-test-*")
+	require.NoError(t, err)
+
+	// delete it once we are done here
+	defer func() { os.RemoveAll(dir) }()
+
+	src, dst := path.Join(dir, "repository"), path.Join(dir, "story")
+	for p, content := range map[string]string{
+		"repository/.env":                   "SECRET=1",
+		"repository/config/app.local.yaml":  "local: true",
+		"repository/config/app.yaml":        "local: false",
+		"repository/certs/dev/server.pem":   "certificate",
+		"repository/.git/config":            "[core]",
+		"repository/existing":               "from the repository",
+		"story/existing":                    "from the story",
+		"story/config/app.yaml":             "local: false",
+		"repository/not-copied/certificate": "certificate",
+	} {
+		require.NoError(t, os.MkdirAll(path.Dir(path.Join(dir, p)), 0755))
+		require.NoError(t, ioutil.WriteFile(path.Join(dir, p), []byte(content), 0600))
+	}
+
+	m := &Manifest{Copy: []string{".env", "config/*.local.yaml", "certs", "existing", ".git", "missing"}}
+	require.NoError(t, m.CopyFiles(src, dst))
+
+	for p, content := range map[string]string{
+		".env":                  "SECRET=1",
+		"config/app.local.yaml": "local: true",
+		"certs/dev/server.pem":  "certificate",
+		"existing":              "from the story",
+	} {
+		got, err := ioutil.ReadFile(path.Join(dst, p))
+		if assert.NoError(t, err) {
+			assert.Equal(t, content, string(got))
+		}
+	}
+	fi, err := os.Stat(path.Join(dst, ".env"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+
+	assert.NoFileExists(t, path.Join(dst, "not-copied", "certificate"))
+	assert.NoDirExists(t, path.Join(dst, ".git"))
+}
+
+func TestTrust(t *testing.T) {
+	// create a temporary directory
+	dir, err := ioutil.TempDir("", "swm-test-*")
+	require.NoError(t, err)
+
+	// delete it once we are done here
+	defer func() { os.RemoveAll(dir) }()
+
+	// keep the trusted manifests within the temporary directory
+	xdg.DataHome = dir
+	defer xdg.Reload()
+
+	load := func(repo, content string) *Manifest {
+		require.NoError(t, os.MkdirAll(repo, 0755))
+		require.NoError(t, ioutil.WriteFile(path.Join(repo, FileName), []byte(content), 0644))
+		m, err := Load(repo)
+		require.NoError(t, err)
+		return m
+	}
+	trusted := func(m *Manifest) bool {
+		ok, err := m.Trusted()
+		require.NoError(t, err)
+		return ok
+	}
+
+	repo1 := path.Join(dir, "repo1")
+	assert.True(t, trusted(load(repo1, "copy:\n  - .env\n")), "a manifest copying files is trusted")
+	for _, content := range []string{
+		"setup:\n  - ./setup.sh\n",
+		"env:\n  - LD_PRELOAD=./evil.so\n",
+		"layout: go-service\n",
+		"layout:\n  windows:\n    - name: editor\n      command: ./evil\n",
+	} {
+		assert.False(t, trusted(load(repo1, content)), content)
+	}
+
+	content := "setup:\n  - ./setup.sh\nenv:\n  - FOO=bar\n"
+	m := load(repo1, content)
+	assert.Equal(t, repo1, m.Repository)
+	require.NoError(t, m.Trust())
+	assert.True(t, trusted(load(repo1, content)))
+
+	// a change to the manifest, even a comment, is not trusted
+	assert.False(t, trusted(load(repo1, content+"# ./setup.sh changed\n")))
+
+	// the same manifest in another repository is not trusted
+	assert.False(t, trusted(load(path.Join(dir, "repo2"), content)))
+
+	// the manifest of a story is trusted by the repository of the story
+	m = load(path.Join(dir, "stories", "STORY-123", "repo1"), content)
+	assert.False(t, trusted(m))
+	m.Repository = repo1
+	assert.True(t, trusted(m))
+}
@@ -0,0 +1,67 @@
+package manifest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/adrg/xdg"
+	"github.com/pkg/errors"
+)
+
+// ErrNotTrusted is returned if the manifest is used before it was trusted
+// with Trust.
+var ErrNotTrusted = errors.New("the manifest is not trusted")
+
+// Trusted returns true if the manifest may be used: it has no setup
+// commands, environment or layout, or it was trusted with Trust. The trust
+// is bound to the repository and to the content of the manifest, a manifest
+// that changed is no longer trusted.
+func (m *Manifest) Trusted() (bool, error) {
+	if len(m.Setup) == 0 && len(m.Env) == 0 && m.Layout == nil && m.LayoutName == "" {
+		return true, nil
+	}
+	if m.Repository == "" {
+		return false, nil
+	}
+
+	if _, err := os.Stat(m.trustPath()); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, errors.Wrap(err, "error reading the trusted manifests")
+	}
+
+	return true, nil
+}
+
+// Trust allows the manifest to be used within its repository.
+func (m *Manifest) Trust() error {
+	if m.Repository == "" {
+		return errors.New("the repository of the manifest is unknown")
+	}
+
+	p := m.trustPath()
+	if err := os.MkdirAll(path.Dir(p), 0700); err != nil {
+		return errors.Wrap(err, "error creating the directory of the trusted manifests")
+	}
+
+	// the file records what was trusted
+	content := append([]byte("# "+m.Repository+"\n"), m.raw...)
+
+	return ioutil.WriteFile(p, content, 0600)
+}
+
+// trustPath returns the path of the file recording that the manifest is
+// trusted, it's named after the hash of the repository and of the content of
+// the manifest.
+func (m *Manifest) trustPath() string {
+	h := sha256.New()
+	h.Write([]byte(m.Repository))
+	h.Write([]byte{0})
+	h.Write(m.raw)
+
+	return path.Join(xdg.DataHome, "swm", "trusted-manifests", hex.EncodeToString(h.Sum(nil)))
+}
@@ -0,0 +1,84 @@
+package multiplexer
+
+import (
+	"strings"
+
+	"github.com/kalbasit/swm/ifaces"
+)
+
+// Fake is a multiplexer for the tests, it records the calls and keeps the
+// sessions in memory.
+type Fake struct {
+	// Running are the names of the running sessions
+	Running []string
+
+	// Attached is the name of the last session attached
+	Attached string
+
+	// Env is the environment set by session name
+	Env map[string][]string
+
+	// Keys are the keys sent, by target
+	Keys map[string][]string
+
+	// Killed is set once Kill is called, with the value of graceful
+	Killed *bool
+
+	// Closed is set once Close is called
+	Closed bool
+}
+
+func (f *Fake) Name() string { return "fake" }
+
+func (f *Fake) SessionName(p ifaces.Project) string {
+	return strings.Replace(p.String(), "/", "_", -1)
+}
+
+func (f *Fake) EnsureSession(p ifaces.Project) (string, error) {
+	sessionName := f.SessionName(p)
+	for _, name := range f.Running {
+		if name == sessionName {
+			return sessionName, nil
+		}
+	}
+	f.Running = append(f.Running, sessionName)
+
+	return sessionName, nil
+}
+
+func (f *Fake) Attach(sessionName string, killPane bool) error {
+	f.Attached = sessionName
+	return nil
+}
+
+func (f *Fake) SetEnv(sessionName string, env []string) error {
+	if f.Env == nil {
+		f.Env = make(map[string][]string)
+	}
+	f.Env[sessionName] = append(f.Env[sessionName], env...)
+
+	return nil
+}
+
+func (f *Fake) Sessions() ([]string, error) { return f.Running, nil }
+
+func (f *Fake) Kill(graceful bool) error {
+	f.Killed = &graceful
+	f.Running = nil
+
+	return nil
+}
+
+func (f *Fake) SendKeys(target string, keys ...string) error {
+	if f.Keys == nil {
+		f.Keys = make(map[string][]string)
+	}
+	f.Keys[target] = append(f.Keys[target], keys...)
+
+	return nil
+}
+
+func (f *Fake) Close() error {
+	f.Closed = true
+	return nil
+}
@@ -0,0 +1,90 @@
+package multiplexer
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/kalbasit/swm/ifaces"
+	"github.com/kalbasit/swm/manifest"
+	"github.com/kalbasit/swm/story"
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrUnknownMultiplexer is returned by New if the multiplexer is not one
+	// of the supported multiplexers
+	ErrUnknownMultiplexer = errors.New("unknown multiplexer")
+
+	// ErrNotSupported is returned if the multiplexer cannot perform the
+	// operation
+	ErrNotSupported = errors.New("not supported by the multiplexer")
+
+	// ErrSessionNotFound is returned if the session is not running
+	ErrSessionNotFound = errors.New("session not found")
+)
+
+// The names of the multiplexers.
+const (
+	// Tmux is the default multiplexer, it's implemented by the tmux package
+	Tmux   = "tmux"
+	Zellij = "zellij"
+	Shell  = "shell"
+)
+
+// Names returns the names of all the multiplexers.
+func Names() []string {
+	return []string{Tmux, Zellij, Shell}
+}
+
+// New returns the multiplexer named name running the sessions of the story,
+// the sessions outside of any story if storyName is empty. Tmux is not
+// returned by New, the tmux manager runs the sessions on tmux unless it's
+// given another multiplexer.
+func New(name, storyName string) (ifaces.Multiplexer, error) {
+	var s ifaces.Story
+	if storyName != "" {
+		var err error
+		if s, err = story.Load(storyName); err != nil {
+			return nil, errors.Wrap(err, "error loading the story")
+		}
+	}
+
+	switch name {
+	case Zellij:
+		return &zellij{story: s}, nil
+	case Shell:
+		return &shell{story: s, sessions: make(map[string]*shellSession)}, nil
+	case Tmux:
+		return nil, errors.Wrap(ErrUnknownMultiplexer, "tmux is run by the tmux manager")
+	default:
+		return nil, errors.Wrapf(ErrUnknownMultiplexer, "%q is not one of %s", name, strings.Join(Names(), ", "))
+	}
+}
+
+// sessionEnv returns the environment of the session of the project: the
+// current environment, outside of any multiplexer, with the variables of the
+// story and the variables of the manifest of the project.
+func sessionEnv(s ifaces.Story, p ifaces.Project) ([]string, error) {
+	m, err := manifest.Load(p.Path(s))
+	if err != nil {
+		return nil, err
+	}
+
+	var res []string
+	for _, v := range os.Environ() {
+		switch strings.Split(v, "=")[0] {
+		case "SWM_STORY_NAME", "SWM_STORY_BRANCH_NAME", "TMUX", "TMUX_PANE", "ZELLIJ", "ZELLIJ_SESSION_NAME":
+		default:
+			res = append(res, v)
+		}
+	}
+	if s != nil {
+		res = append(res,
+			fmt.Sprintf("SWM_STORY_NAME=%s", s.GetName()),
+			fmt.Sprintf("SWM_STORY_BRANCH_NAME=%s", s.GetBranchName()),
+		)
+	}
+
+	return append(res, m.Env...), nil
+}
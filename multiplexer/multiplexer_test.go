@@ -0,0 +1,121 @@
+package multiplexer
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"testing"
+
+	"github.com/adrg/xdg"
+	"github.com/kalbasit/swm/code"
+	"github.com/kalbasit/swm/ifaces"
+	"github.com/kalbasit/swm/manifest"
+	"github.com/kalbasit/swm/story"
+	"github.com/kalbasit/swm/testhelper"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	for _, name := range []string{Zellij, Shell} {
+		mux, err := New(name, "")
+		require.NoError(t, err)
+		assert.Equal(t, name, mux.Name())
+	}
+
+	for _, name := range []string{Tmux, "screen"} {
+		_, err := New(name, "")
+		assert.True(t, errors.Is(err, ErrUnknownMultiplexer))
+	}
+}
+
+func TestZellijSessionName(t *testing.T) {
+	prj := newProject(t)
+
+	z := &zellij{}
+	assert.Equal(t, "swm@github.com_owner1_repo1", z.SessionName(prj))
+
+	s, err := story.New("feature/STORY-123", "")
+	require.NoError(t, err)
+	z = &zellij{story: s}
+	assert.Equal(t, "swm-feature_STORY-123@github.com_owner1_repo1", z.SessionName(prj))
+}
+
+func TestParseZellijSessions(t *testing.T) {
+	out := "swm@github.com_owner1_repo1 [Created 2h 3m ago] (current)\n" +
+		"swm-STORY-123@github.com_owner2_repo2 [Created 5m ago] \n" +
+		"swm@github.com_owner3_repo3 [Created 1day ago] (EXITED - attach to resurrect)\n"
+
+	assert.Equal(t, []string{"swm@github.com_owner1_repo1", "swm-STORY-123@github.com_owner2_repo2"}, parseZellijSessions(out))
+	assert.Empty(t, parseZellijSessions(zellijNoSessions+".\n"))
+}
+
+func TestZellijKeyAction(t *testing.T) {
+	for key, want := range map[string][]string{
+		"Enter":   {"write", "13"},
+		"C-[":     {"write", "27"},
+		"C-c":     {"write", "3"},
+		":xa":     {"write-chars", ":xa"},
+		"C-SPACE": {"write-chars", "C-SPACE"},
+	} {
+		assert.Equal(t, want, zellijKeyAction(key), key)
+	}
+}
+
+func TestShell(t *testing.T) {
+	prj := newProject(t)
+	require.NoError(t, ioutil.WriteFile(path.Join(prj.Path(nil), manifest.FileName), []byte("env:\n  - GOFLAGS=-mod=vendor\n"), 0644))
+
+	s, err := story.New("STORY-123", "")
+	require.NoError(t, err)
+	sh := &shell{sessions: make(map[string]*shellSession)}
+
+	sessionName, err := sh.EnsureSession(prj)
+	require.NoError(t, err)
+	assert.Equal(t, "github.com/owner1/repo1", sessionName)
+	assert.Equal(t, prj.Path(nil), sh.sessions[sessionName].dir)
+	assert.Contains(t, sh.sessions[sessionName].env, "GOFLAGS=-mod=vendor")
+
+	require.NoError(t, sh.SetEnv(sessionName, []string{"FOO=bar"}))
+	assert.Contains(t, sh.sessions[sessionName].env, "FOO=bar")
+	assert.True(t, errors.Is(sh.SetEnv("github.com/owner2/repo2", nil), ErrSessionNotFound))
+	assert.True(t, errors.Is(sh.SendKeys(sessionName, "Enter"), ErrNotSupported))
+
+	names, err := sh.Sessions()
+	require.NoError(t, err)
+	assert.Empty(t, names)
+
+	t.Run("story", func(t *testing.T) {
+		env, err := sessionEnv(s, prj)
+		require.NoError(t, err)
+		assert.Contains(t, env, "SWM_STORY_NAME=STORY-123")
+	})
+}
+
+// newProject returns the project github.com/owner1/repo1 of a temporary code.
+func newProject(t *testing.T) ifaces.Project {
+	// create a temporary directory
+	dir, err := ioutil.TempDir("", "swm-test-*")
+	require.NoError(t, err)
+
+	// delete it once we are done here
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	// keep the index of the code within the temporary directory
+	xdg.CacheHome = dir
+	t.Cleanup(xdg.Reload)
+
+	// create the filesystem we want to scan
+	require.NoError(t, testhelper.CreateProjects(dir))
+
+	// create a code
+	c := code.New(dir, regexp.MustCompile("^.snapshots$"))
+	require.NoError(t, c.Scan())
+
+	prj, err := c.GetProjectByRelativePath("github.com/owner1/repo1")
+	require.NoError(t, err)
+
+	return prj
+}
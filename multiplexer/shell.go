@@ -0,0 +1,93 @@
+package multiplexer
+
+import (
+	"os"
+	"path"
+	"syscall"
+
+	"github.com/kalbasit/swm/ifaces"
+	"github.com/pkg/errors"
+)
+
+// defaultShell is run if $SHELL is not set.
+const defaultShell = "/bin/sh"
+
+// shell spawns $SHELL in the directory of the project, with the environment
+// of the story, for the machines without a multiplexer. The shells are not
+// tracked once they're spawned so no session is ever running.
+type shell struct {
+	story    ifaces.Story
+	sessions map[string]*shellSession
+}
+
+// shellSession is a shell ready to be spawned.
+type shellSession struct {
+	dir string
+	env []string
+}
+
+func (s *shell) Name() string { return Shell }
+
+// SessionName returns the import path of the project.
+func (s *shell) SessionName(p ifaces.Project) string {
+	return p.String()
+}
+
+// EnsureSession prepares the shell of the project, it's spawned by Attach.
+func (s *shell) EnsureSession(p ifaces.Project) (string, error) {
+	sessionName := s.SessionName(p)
+	if _, ok := s.sessions[sessionName]; ok {
+		return sessionName, nil
+	}
+
+	env, err := sessionEnv(s.story, p)
+	if err != nil {
+		return "", err
+	}
+	s.sessions[sessionName] = &shellSession{dir: p.Path(s.story), env: env}
+
+	return sessionName, nil
+}
+
+// Attach replaces swm with the shell of the session.
+func (s *shell) Attach(sessionName string, killPane bool) error {
+	session, ok := s.sessions[sessionName]
+	if !ok {
+		return errors.Wrap(ErrSessionNotFound, sessionName)
+	}
+
+	sh := os.Getenv("SHELL")
+	if sh == "" {
+		sh = defaultShell
+	}
+	if err := os.Chdir(session.dir); err != nil {
+		return errors.Wrapf(err, "error changing the directory to %s", session.dir)
+	}
+
+	return syscall.Exec(sh, []string{path.Base(sh)}, session.env)
+}
+
+// SetEnv adds the variables to the environment of the shell.
+func (s *shell) SetEnv(sessionName string, env []string) error {
+	session, ok := s.sessions[sessionName]
+	if !ok {
+		return errors.Wrap(ErrSessionNotFound, sessionName)
+	}
+	session.env = append(session.env, env...)
+
+	return nil
+}
+
+// Sessions returns no session, the spawned shells are not tracked.
+func (s *shell) Sessions() ([]string, error) { return nil, nil }
+
+// Kill does nothing, the spawned shells are not tracked.
+func (s *shell) Kill(graceful bool) error { return nil }
+
+// SendKeys is not supported, the shell is not spawned until Attach replaces
+// swm with it.
+func (s *shell) SendKeys(target string, keys ...string) error {
+	return errors.Wrapf(ErrNotSupported, "cannot send keys to the shell of %s", target)
+}
+
+func (s *shell) Close() error { return nil }
@@ -0,0 +1,202 @@
+package multiplexer
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/kalbasit/swm/ifaces"
+	"github.com/kalbasit/swm/tools"
+	"github.com/pkg/errors"
+)
+
+// zellijNoSessions is printed by zellij list-sessions if no session is
+// running.
+const zellijNoSessions = "No active zellij sessions found"
+
+// zellij runs the sessions on zellij. Zellij has one server per session, the
+// sessions of a story are prefixed with the name of the story. The layouts of
+// swm are not used, the sessions start with the default layout of zellij.
+type zellij struct {
+	story ifaces.Story
+}
+
+func (z *zellij) Name() string { return Zellij }
+
+// SessionName returns the name of the session of the project, zellij names
+// the socket of the session after it so it cannot contain a slash.
+func (z *zellij) SessionName(p ifaces.Project) string {
+	return z.prefix() + strings.Replace(p.String(), "/", "_", -1)
+}
+
+// prefix returns the prefix of the sessions of the story.
+func (z *zellij) prefix() string {
+	if z.story != nil {
+		return "swm-" + strings.Replace(z.story.GetName(), "/", "_", -1) + "@"
+	}
+
+	return "swm@"
+}
+
+// EnsureSession starts the session of the project in the background, within
+// the directory of the project and with the environment of the story.
+func (z *zellij) EnsureSession(p ifaces.Project) (string, error) {
+	sessionName := z.SessionName(p)
+
+	running, err := z.Sessions()
+	if err != nil {
+		return "", err
+	}
+	for _, name := range running {
+		if name == sessionName {
+			return sessionName, nil
+		}
+	}
+
+	env, err := sessionEnv(z.story, p)
+	if err != nil {
+		return "", err
+	}
+	cmd, err := tools.Command(tools.Zellij, "attach", "--create-background", sessionName)
+	if err != nil {
+		return "", err
+	}
+	cmd.Dir = p.Path(z.story)
+	cmd.Env = env
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return "", errors.Wrapf(err, "error creating the zellij session %s: %s", sessionName, strings.TrimSpace(string(out)))
+	}
+
+	return sessionName, nil
+}
+
+// Attach replaces swm with a zellij client attached to the session. Zellij
+// cannot switch the client of another session, so the session is not
+// attached from within zellij.
+func (z *zellij) Attach(sessionName string, killPane bool) error {
+	if os.Getenv("ZELLIJ") != "" {
+		return errors.Wrapf(ErrNotSupported, "cannot attach %s from within zellij, detach from %s first", sessionName, os.Getenv("ZELLIJ_SESSION_NAME"))
+	}
+
+	zellijPath, err := tools.Path(tools.Zellij)
+	if err != nil {
+		return err
+	}
+
+	return syscall.Exec(zellijPath, []string{"zellij", "attach", sessionName}, os.Environ())
+}
+
+// SetEnv is not supported, the environment of a zellij session is the one it
+// was created with.
+func (z *zellij) SetEnv(sessionName string, env []string) error {
+	return errors.Wrapf(ErrNotSupported, "cannot set the environment of the zellij session %s", sessionName)
+}
+
+// Sessions returns the names of the running sessions of the story, the
+// exited sessions that zellij can resurrect are not running.
+func (z *zellij) Sessions() ([]string, error) {
+	cmd, err := tools.Command(tools.Zellij, "list-sessions", "--no-formatting")
+	if err != nil {
+		return nil, err
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		if ee, ok := err.(*exec.ExitError); ok && strings.Contains(string(out)+string(ee.Stderr), zellijNoSessions) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "error listing the zellij sessions")
+	}
+
+	var names []string
+	for _, name := range parseZellijSessions(string(out)) {
+		if strings.HasPrefix(name, z.prefix()) {
+			names = append(names, name)
+		}
+	}
+
+	return names, nil
+}
+
+// parseZellijSessions returns the running sessions listed by zellij
+// list-sessions --no-formatting, one per line such as:
+//
+//	name [Created 2h 3m ago] (current)
+//	name [Created 1day ago] (EXITED - attach to resurrect)
+func parseZellijSessions(out string) []string {
+	var names []string
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.Contains(line, "(EXITED") || strings.Contains(line, zellijNoSessions) {
+			continue
+		}
+		names = append(names, fields[0])
+	}
+
+	return names
+}
+
+// Kill kills the sessions of the story. Zellij does not report the processes
+// running in its panes, so the protected processes are not looked for and
+// graceful has no effect.
+func (z *zellij) Kill(graceful bool) error {
+	names, err := z.Sessions()
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		cmd, err := tools.Command(tools.Zellij, "kill-session", name)
+		if err != nil {
+			return err
+		}
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return errors.Wrapf(err, "error killing the zellij session %s: %s", name, strings.TrimSpace(string(out)))
+		}
+	}
+
+	return nil
+}
+
+// SendKeys writes the keys to the focused pane of the session.
+func (z *zellij) SendKeys(target string, keys ...string) error {
+	for _, key := range keys {
+		cmd, err := tools.Command(tools.Zellij, append([]string{"--session", target, "action"}, zellijKeyAction(key)...)...)
+		if err != nil {
+			return err
+		}
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return errors.Wrapf(err, "error sending the keys to %s: %s", target, strings.TrimSpace(string(out)))
+		}
+	}
+
+	return nil
+}
+
+// zellijKeyBytes are the bytes written for the key names of tmux.
+var zellijKeyBytes = map[string]byte{
+	"Enter":  '\r',
+	"Escape": 0x1b,
+	"Tab":    '\t',
+	"Space":  ' ',
+	"BSpace": 0x7f,
+}
+
+// zellijKeyAction returns the action writing the key, a key name as known by
+// tmux send-keys (Enter, C-c) is written as its byte and anything else as
+// text.
+func zellijKeyAction(key string) []string {
+	if b, ok := zellijKeyBytes[key]; ok {
+		return []string{"write", strconv.Itoa(int(b))}
+	}
+	if len(key) == 3 && strings.HasPrefix(key, "C-") {
+		// the control characters, C-[ is the escape key
+		if c := key[2]; (c >= 'a' && c <= 'z') || c == '[' {
+			return []string{"write", strconv.Itoa(int(c & 0x1f))}
+		}
+	}
+
+	return []string{"write-chars", key}
+}
+
+func (z *zellij) Close() error { return nil }
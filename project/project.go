@@ -9,8 +9,11 @@ import (
 	"path"
 	"strings"
 
+	"github.com/adrg/xdg"
 	"github.com/google/go-github/github"
 	"github.com/kalbasit/swm/ifaces"
+	"github.com/kalbasit/swm/manifest"
+	"github.com/kalbasit/swm/vcs"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 )
@@ -20,9 +23,6 @@ const srcDir = "src"
 var (
 	// ErrNoActiveStory is returned if there's no active story
 	ErrNoActiveStory = errors.New("no story is active")
-
-	// gitPath is the PATH to the git binary
-	gitPath string
 )
 
 type project struct {
@@ -31,14 +31,10 @@ type project struct {
 
 	// importPath is the path of the project relative to the GOPATH/src of the profile/workspace
 	importPath string
-}
 
-func init() {
-	var err error
-	gitPath, err = exec.LookPath("git")
-	if err != nil {
-		log.Fatal().Msgf("error looking up the git executable, is it installed? %s", err)
-	}
+	// vcs is the version control system of this project, it's detected on
+	// first use.
+	vcs ifaces.VCS
 }
 
 func New(c ifaces.Code, importPath string) ifaces.Project {
@@ -61,6 +57,19 @@ func (p *project) String() string { return p.importPath }
 
 func (p *project) Code() ifaces.Code { return p.code }
 
+// VCS returns the version control system of this project.
+func (p *project) VCS() (ifaces.VCS, error) {
+	if p.vcs == nil {
+		v, err := vcs.Detect(p.repositoryPath())
+		if err != nil {
+			return nil, errors.Wrapf(err, "error detecting the version control system of %s", p.importPath)
+		}
+		p.vcs = v
+	}
+
+	return p.vcs, nil
+}
+
 // ListPullRequests returns the list of pull requests.
 func (p *project) ListPullRequests(ghc *github.Client) ([]*github.PullRequest, error) {
 	prs, _, err := ghc.PullRequests.List(context.Background(), p.owner(), p.repo(), nil)
@@ -92,11 +101,20 @@ func (p *project) CreateStory(s ifaces.Story) error {
 			return err
 		}
 
-		log.Debug().
+		if !p.setupPending(s) {
+			log.Debug().
+				Str("import-path", p.importPath).
+				Str("story-path", wp).
+				Msg("the story already exists")
+			return nil
+		}
+
+		// the setup of the story failed, or was not trusted, the last time
+		log.Info().
 			Str("import-path", p.importPath).
 			Str("story-path", wp).
-			Msg("the story already exists")
-		return nil
+			Msg("resuming the setup of the story")
+		return p.finishStory(s)
 	}
 
 	// run the pre-hooks
@@ -108,12 +126,38 @@ func (p *project) CreateStory(s ifaces.Story) error {
 		return err
 	}
 	// create a new story for this project based on the base project
-	// TODO(kalbasit): switch to using [go-git](https://github.com/src-d/go-git)
-	cmd := exec.Command(gitPath, "worktree", "add", "-B", sbn, wp)
-	cmd.Dir = p.repositoryPath()
-	out, err := cmd.CombinedOutput()
+	v, err := p.VCS()
 	if err != nil {
-		return fmt.Errorf("error creating a new story: %s\nOutput:\n%s", err, string(out))
+		return err
+	}
+	if err := v.CreateWorkspace(p.repositoryPath(), wp, sbn); err != nil {
+		return errors.Wrap(err, "error creating a new story")
+	}
+	// the setup of the manifest is resumed by the next CreateStory until it
+	// completes
+	if _, err := os.Stat(path.Join(wp, manifest.FileName)); err == nil {
+		if err := p.markSetupPending(s, true); err != nil {
+			return err
+		}
+	}
+
+	return p.finishStory(s)
+}
+
+// finishStory sets up the new story, then runs the post-hooks.
+func (p *project) finishStory(s ifaces.Story) error {
+	wp := p.storyPath(s)
+
+	// prepare the story as described by the manifest of the project
+	if err := p.setup(s); err != nil {
+		log.Error().
+			Str("import-path", p.importPath).
+			Str("story-path", wp).
+			Msg("error setting up the story")
+		return err
+	}
+	if err := p.markSetupPending(s, false); err != nil {
+		return err
 	}
 	// run the post-hooks
 	if err := p.runPostHooks(s); err != nil {
@@ -132,6 +176,105 @@ func (p *project) CreateStory(s ifaces.Story) error {
 	return nil
 }
 
+// RemoveStory removes the story path for this project.
+func (p *project) RemoveStory(s ifaces.Story) error {
+	wp := p.storyPath(s)
+
+	v, err := p.VCS()
+	if err != nil {
+		return err
+	}
+	if err := v.RemoveWorkspace(p.repositoryPath(), wp); err != nil {
+		return errors.Wrap(err, "error removing the story")
+	}
+	if err := p.markSetupPending(s, false); err != nil {
+		return err
+	}
+
+	log.Debug().
+		Str("import-path", p.importPath).
+		Str("story-path", wp).
+		Msg("story removed successfully")
+
+	return nil
+}
+
+// setup copies the files listed by the manifest of the project from the
+// repository to the story, then runs the setup commands of the manifest
+// within the story. The manifest comes from the repository, its setup
+// commands only run once the user trusted it (swm code trust).
+func (p *project) setup(s ifaces.Story) error {
+	wp := p.storyPath(s)
+
+	m, err := manifest.Load(wp)
+	if err != nil {
+		return err
+	}
+	m.Repository = p.repositoryPath()
+	trusted, err := m.Trusted()
+	if err != nil {
+		return err
+	}
+	if !trusted {
+		return errors.Wrapf(manifest.ErrNotTrusted, "review %s and run swm code trust %s", path.Join(wp, manifest.FileName), wp)
+	}
+
+	if err := m.CopyFiles(p.repositoryPath(), wp); err != nil {
+		return errors.Wrap(err, "error copying the files of the manifest")
+	}
+
+	env := append(os.Environ(), m.Env...)
+	env = append(env,
+		fmt.Sprintf("SWM_STORY_NAME=%s", s.GetName()),
+		fmt.Sprintf("SWM_STORY_BRANCH_NAME=%s", s.GetBranchName()),
+	)
+	for _, command := range m.Setup {
+		log.Debug().
+			Str("import-path", p.importPath).
+			Str("command", command).
+			Msg("running a setup command")
+		cmd := exec.Command("sh", "-c", command)
+		cmd.Dir = wp
+		cmd.Env = env
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("error running the setup command %q: %s\nOutput:\n%s", command, err, string(out))
+		}
+	}
+
+	return nil
+}
+
+// setupPendingPath returns the path of the file marking the setup of the
+// story as pending.
+func (p *project) setupPendingPath(s ifaces.Story) string {
+	return path.Join(xdg.DataHome, "swm", "setup-pending", s.GetName(), p.importPath)
+}
+
+// setupPending returns true if the story was created but its setup did not
+// complete.
+func (p *project) setupPending(s ifaces.Story) bool {
+	_, err := os.Stat(p.setupPendingPath(s))
+	return err == nil
+}
+
+// markSetupPending marks the setup of the story as pending, or as complete.
+func (p *project) markSetupPending(s ifaces.Story, pending bool) error {
+	fp := p.setupPendingPath(s)
+	if !pending {
+		if err := os.Remove(fp); err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "error marking the setup of the story as complete")
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(path.Dir(fp), 0755); err != nil {
+		return errors.Wrap(err, "error marking the setup of the story as pending")
+	}
+
+	return errors.Wrap(ioutil.WriteFile(fp, nil, 0644), "error marking the setup of the story as pending")
+}
+
 func (p *project) owner() string {
 	parts := strings.Split(p.importPath, "/")
 	if len(parts) != 3 {
@@ -3,12 +3,16 @@ package project
 import (
 	"io/ioutil"
 	"os"
+	"os/exec"
 	"path"
 	"testing"
 
+	"github.com/adrg/xdg"
 	"github.com/kalbasit/swm/ifaces"
+	"github.com/kalbasit/swm/manifest"
 	"github.com/kalbasit/swm/story"
 	"github.com/kalbasit/swm/testhelper"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -17,12 +21,13 @@ type code struct {
 	path string
 }
 
-func (c *code) Clone(url string) error                                  { return nil }
+func (c *code) Clone(url, vcsName string) error                         { return nil }
 func (c *code) GetProjectByAbsolutePath(string) (ifaces.Project, error) { return nil, nil }
 func (c *code) GetProjectByRelativePath(string) (ifaces.Project, error) { return nil, nil }
 func (c *code) HookPath() string                                        { return "" }
 func (c *code) Path() string                                            { return c.path }
 func (c *code) Projects() []ifaces.Project                              { return nil }
+func (c *code) Rebuild() error                                          { return nil }
 func (c *code) RepositoriesDir() string                                 { return path.Join(c.path, "repositories") }
 func (c *code) Scan() error                                             { return nil }
 func (c *code) StoriesDir() string                                      { return path.Join(c.path, "stories") }
@@ -69,6 +74,108 @@ func TestCreateStory(t *testing.T) {
 	}
 }
 
+func TestCreateStoryWithManifest(t *testing.T) {
+	// create a temporary directory
+	dir, err := ioutil.TempDir("", "swm-test-*")
+	require.NoError(t, err)
+
+	// delete it once we are done here
+	defer func() { os.RemoveAll(dir) }()
+
+	// keep the trusted manifests within the temporary directory
+	xdg.DataHome = dir
+	defer xdg.Reload()
+
+	// create the filesystem we want to scan
+	require.NoError(t, testhelper.CreateProjects(dir))
+
+	// commit the manifest, and leave the .env file untracked
+	rp := path.Join(dir, "repositories", "github.com/owner1/repo1")
+	require.NoError(t, ioutil.WriteFile(path.Join(rp, manifest.FileName), []byte(`
+setup:
+  - echo "$GREETING $SWM_STORY_NAME" > setup.out
+env:
+  - GREETING=hello
+copy:
+  - .env
+`), 0644))
+	for _, args := range [][]string{
+		{"add", manifest.FileName},
+		{"commit", "--no-verify", "--no-gpg-sign", "--message", "add the manifest"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = rp
+		cmd.Env = append(os.Environ(), testhelper.GitIdentity...)
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	require.NoError(t, ioutil.WriteFile(path.Join(rp, ".env"), []byte("SECRET=1"), 0600))
+
+	// create a code
+	c := &code{path: dir}
+
+	// create the story
+	s, err := story.New("STORY-123", "")
+	require.NoError(t, err)
+
+	// the setup commands do not run before the manifest is trusted, the
+	// setup is resumed once it is
+	prj := New(c, "github.com/owner1/repo1")
+	err = prj.CreateStory(s)
+	assert.True(t, errors.Is(err, manifest.ErrNotTrusted))
+	assert.NoFileExists(t, path.Join(prj.Path(s), "setup.out"))
+
+	m, err := manifest.Load(prj.Path(s))
+	require.NoError(t, err)
+	m.Repository = rp
+	require.NoError(t, m.Trust())
+	require.NoError(t, prj.CreateStory(s))
+
+	out, err := ioutil.ReadFile(path.Join(prj.Path(s), "setup.out"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello STORY-123\n", string(out))
+
+	env, err := ioutil.ReadFile(path.Join(prj.Path(s), ".env"))
+	require.NoError(t, err)
+	assert.Equal(t, "SECRET=1", string(env))
+
+	// the setup completed, it is not run again
+	require.NoError(t, os.Remove(path.Join(prj.Path(s), "setup.out")))
+	require.NoError(t, prj.CreateStory(s))
+	assert.NoFileExists(t, path.Join(prj.Path(s), "setup.out"))
+}
+
+func TestRemoveStory(t *testing.T) {
+	// create a temporary directory
+	dir, err := ioutil.TempDir("", "swm-test-*")
+	require.NoError(t, err)
+
+	// delete it once we are done here
+	defer func() { os.RemoveAll(dir) }()
+
+	// create the filesystem we want to scan
+	require.NoError(t, testhelper.CreateProjects(dir))
+
+	// create a code
+	c := &code{path: dir}
+
+	// create the story
+	s, err := story.New(t.Name(), "")
+	require.NoError(t, err)
+
+	prj := New(c, "github.com/owner1/repo1")
+	require.NoError(t, prj.CreateStory(s))
+	require.DirExists(t, prj.Path(s))
+
+	v, err := prj.VCS()
+	require.NoError(t, err)
+	assert.Equal(t, "git", v.Name())
+
+	if assert.NoError(t, prj.RemoveStory(s)) {
+		assert.NoDirExists(t, prj.Path(s))
+	}
+}
+
 func TestString(t *testing.T) {
 	assert.Equal(t, "github.com/kalbasit/swm", (&project{importPath: "github.com/kalbasit/swm"}).String())
 }
@@ -0,0 +1,259 @@
+package selector
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	enterAltScreen = "\x1b[?1049h"
+	exitAltScreen  = "\x1b[?1049l"
+	clearScreen    = "\x1b[H\x1b[2J"
+	reverseVideo   = "\x1b[7m"
+	resetVideo     = "\x1b[0m"
+)
+
+// The keys understood by the finder.
+const (
+	keyNone = iota
+	keyRune
+	keyEnter
+	keyAbort
+	keyUp
+	keyDown
+	keyBackspace
+	keyClearQuery
+	keyDeleteWord
+)
+
+// builtin is a fuzzy finder running in the terminal, it does not require any
+// external tool.
+type builtin struct{}
+
+func (*builtin) Select(items []string) (string, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return "", errors.Wrap(err, "error opening the terminal")
+	}
+	defer tty.Close()
+
+	fd := int(tty.Fd())
+	restore, err := makeRaw(fd)
+	if err != nil {
+		return "", err
+	}
+	defer restore()
+
+	width, height := 80, 24
+	if ws, err := unix.IoctlGetWinsize(fd, unix.TIOCGWINSZ); err == nil && ws.Row > 0 {
+		width, height = int(ws.Col), int(ws.Row)
+	}
+
+	io.WriteString(tty, enterAltScreen)
+	defer io.WriteString(tty, exitAltScreen)
+
+	return newFinder(items, width, height).run(bufio.NewReader(tty), tty)
+}
+
+// makeRaw puts the terminal in raw mode and returns the function restoring
+// its previous state.
+func makeRaw(fd int) (func(), error) {
+	old, err := unix.IoctlGetTermios(fd, ioctlGetTermios)
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading the state of the terminal")
+	}
+
+	raw := *old
+	raw.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	raw.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	raw.Cflag &^= unix.CSIZE | unix.PARENB
+	raw.Cflag |= unix.CS8
+	raw.Cc[unix.VMIN] = 1
+	raw.Cc[unix.VTIME] = 0
+	if err := unix.IoctlSetTermios(fd, ioctlSetTermios, &raw); err != nil {
+		return nil, errors.Wrap(err, "error putting the terminal in raw mode")
+	}
+
+	return func() { unix.IoctlSetTermios(fd, ioctlSetTermios, old) }, nil
+}
+
+// finder holds the state of the built-in fuzzy finder.
+type finder struct {
+	items   []string
+	query   []rune
+	matches []string
+	cursor  int
+	width   int
+	height  int
+}
+
+func newFinder(items []string, width, height int) *finder {
+	f := &finder{items: items, width: width, height: height}
+	f.update()
+
+	return f
+}
+
+// update filters the items with the query.
+func (f *finder) update() {
+	f.matches = Filter(string(f.query), f.items)
+	if f.cursor >= len(f.matches) {
+		f.cursor = len(f.matches) - 1
+	}
+	if f.cursor < 0 {
+		f.cursor = 0
+	}
+}
+
+// run renders the finder to w and handles the keys read from r until an
+// item is selected or the selection is aborted.
+func (f *finder) run(r *bufio.Reader, w io.Writer) (string, error) {
+	for {
+		f.render(w)
+
+		key, ch, err := readKey(r)
+		if err != nil {
+			if err == io.EOF {
+				return "", ErrNoSelection
+			}
+			return "", errors.Wrap(err, "error reading from the terminal")
+		}
+
+		switch key {
+		case keyRune:
+			f.query = append(f.query, ch)
+			f.cursor = 0
+		case keyBackspace:
+			if len(f.query) > 0 {
+				f.query = f.query[:len(f.query)-1]
+			}
+		case keyClearQuery:
+			f.query = nil
+		case keyDeleteWord:
+			q := strings.TrimRight(string(f.query), " ")
+			f.query = []rune(q[:strings.LastIndexAny(q, " /")+1])
+		case keyUp:
+			if f.cursor > 0 {
+				f.cursor--
+			}
+		case keyDown:
+			if f.cursor < len(f.matches)-1 {
+				f.cursor++
+			}
+		case keyEnter:
+			if len(f.matches) == 0 {
+				return "", ErrNoSelection
+			}
+			return f.matches[f.cursor], nil
+		case keyAbort:
+			return "", ErrNoSelection
+		}
+		f.update()
+	}
+}
+
+// render draws the prompt followed by the matches that fit the screen.
+func (f *finder) render(w io.Writer) {
+	var b strings.Builder
+	b.WriteString(clearScreen)
+	fmt.Fprintf(&b, "> %s\r\n", string(f.query))
+	fmt.Fprintf(&b, "  %d/%d\r\n", len(f.matches), len(f.items))
+
+	rows := f.height - 2
+	if rows < 1 {
+		rows = 1
+	}
+	offset := 0
+	if f.cursor >= rows {
+		offset = f.cursor - rows + 1
+	}
+	for i := offset; i < len(f.matches) && i < offset+rows; i++ {
+		line := truncate(f.matches[i], f.width-2)
+		if i == f.cursor {
+			fmt.Fprintf(&b, "%s> %s%s", reverseVideo, line, resetVideo)
+		} else {
+			fmt.Fprintf(&b, "  %s", line)
+		}
+		if i < len(f.matches)-1 && i < offset+rows-1 {
+			b.WriteString("\r\n")
+		}
+	}
+
+	// move the cursor back to the end of the prompt
+	fmt.Fprintf(&b, "\x1b[1;%dH", len(f.query)+3)
+
+	io.WriteString(w, b.String())
+}
+
+// readKey reads the next key from the terminal.
+func readKey(r *bufio.Reader) (int, rune, error) {
+	ch, _, err := r.ReadRune()
+	if err != nil {
+		return keyNone, 0, err
+	}
+
+	switch ch {
+	case '\r', '\n':
+		return keyEnter, 0, nil
+	case 0x03, 0x07: // C-c, C-g
+		return keyAbort, 0, nil
+	case 0x7f, 0x08: // backspace, C-h
+		return keyBackspace, 0, nil
+	case 0x15: // C-u
+		return keyClearQuery, 0, nil
+	case 0x17: // C-w
+		return keyDeleteWord, 0, nil
+	case 0x10, 0x0b: // C-p, C-k
+		return keyUp, 0, nil
+	case 0x0e, '\t': // C-n, tab
+		return keyDown, 0, nil
+	case 0x1b:
+		// a lone escape aborts, otherwise it's the start of a sequence
+		if r.Buffered() == 0 {
+			return keyAbort, 0, nil
+		}
+		next, _, err := r.ReadRune()
+		if err != nil {
+			return keyNone, 0, err
+		}
+		if next != '[' && next != 'O' {
+			return keyNone, 0, nil
+		}
+		code, _, err := r.ReadRune()
+		if err != nil {
+			return keyNone, 0, err
+		}
+		switch code {
+		case 'A':
+			return keyUp, 0, nil
+		case 'B':
+			return keyDown, 0, nil
+		}
+		return keyNone, 0, nil
+	}
+
+	if ch < 0x20 {
+		return keyNone, 0, nil
+	}
+
+	return keyRune, ch, nil
+}
+
+// truncate shortens s to at most n runes.
+func truncate(s string, n int) string {
+	if n < 1 {
+		return ""
+	}
+	rs := []rune(s)
+	if len(rs) <= n {
+		return s
+	}
+
+	return string(rs[:n])
+}
@@ -0,0 +1,55 @@
+package selector
+
+import (
+	"bufio"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFinder(t *testing.T) {
+	items := []string{"github•com/owner1/repo1", "github•com/owner2/repo2", "github•com/owner3/repo3"}
+
+	tests := map[string]struct {
+		input     string
+		selection string
+		err       error
+	}{
+		"enter selects the first item":      {"\r", items[0], nil},
+		"the query filters the items":       {"o2r\r", items[1], nil},
+		"the arrows move the cursor":        {"\x1b[B\x1b[B\x1b[A\r", items[1], nil},
+		"the cursor does not go past items": {"\x0e\x0e\x0e\x0e\r", items[2], nil},
+		"backspace edits the query":         {"3x\x7f\x7f2\r", items[1], nil},
+		"C-u clears the query":              {"xyz\x15\r", items[0], nil},
+		"no match":                          {"xyz\r", "", ErrNoSelection},
+		"C-c aborts":                        {"repo\x03", "", ErrNoSelection},
+		"end of input aborts":               {"repo", "", ErrNoSelection},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			f := newFinder(items, 80, 24)
+			selection, err := f.run(bufio.NewReader(strings.NewReader(test.input)), ioutil.Discard)
+			assert.Equal(t, test.err, err)
+			assert.Equal(t, test.selection, selection)
+		})
+	}
+}
+
+func TestFinderRender(t *testing.T) {
+	items := []string{"one", "two", "three", "four"}
+	f := newFinder(items, 80, 4)
+	f.cursor = 3
+
+	var b strings.Builder
+	f.render(&b)
+
+	out := b.String()
+	assert.Contains(t, out, "  4/4")
+	assert.NotContains(t, out, "one")
+	assert.NotContains(t, out, "two")
+	assert.Contains(t, out, "  three")
+	assert.Contains(t, out, reverseVideo+"> four"+resetVideo)
+}
@@ -0,0 +1,40 @@
+package selector
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/kalbasit/swm/tools"
+	"github.com/pkg/errors"
+)
+
+// command is a selector running an external picker that reads the items on
+// its standard input and prints the selection on its standard output.
+type command struct {
+	tool string
+	args []string
+}
+
+func (c *command) Select(items []string) (string, error) {
+	cmd, err := tools.Command(c.tool, c.args...)
+	if err != nil {
+		return "", err
+	}
+	cmd.Stdin = strings.NewReader(strings.Join(items, "\n") + "\n")
+	cmd.Stderr = os.Stderr
+
+	out, err := cmd.Output()
+	// the pickers exit with a non-zero code when the selection is aborted
+	selection := string(bytes.SplitN(out, []byte("\n"), 2)[0])
+	var exitErr *exec.ExitError
+	if selection == "" && (err == nil || errors.As(err, &exitErr)) {
+		return "", ErrNoSelection
+	}
+	if err != nil {
+		return "", errors.Wrapf(err, "error running %s", c.tool)
+	}
+
+	return selection, nil
+}
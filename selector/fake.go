@@ -0,0 +1,30 @@
+package selector
+
+// Fake is a selector for the tests, it records the items it was given and
+// returns a preset selection.
+type Fake struct {
+	// Selection is returned by Select, the first item is returned if it's
+	// empty
+	Selection string
+
+	// Err is returned by Select if set
+	Err error
+
+	// Items are the items given to the last call of Select
+	Items []string
+}
+
+func (f *Fake) Select(items []string) (string, error) {
+	f.Items = items
+	if f.Err != nil {
+		return "", f.Err
+	}
+	if f.Selection != "" {
+		return f.Selection, nil
+	}
+	if len(items) == 0 {
+		return "", ErrNoSelection
+	}
+
+	return items[0], nil
+}
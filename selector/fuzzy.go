@@ -0,0 +1,123 @@
+package selector
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	scoreMatch       = 16
+	bonusConsecutive = 16
+	bonusBoundary    = 8
+	penaltyGap       = 1
+)
+
+// Filter returns the items matching the query ordered by their score, the
+// best match first. Items with the same score keep their order. The query
+// matches an item if all its characters appear in the item in the same order,
+// the match is case insensitive unless the query has an upper case letter.
+func Filter(query string, items []string) []string {
+	if query == "" {
+		return items
+	}
+
+	type match struct {
+		item  string
+		score int
+	}
+
+	var matches []match
+	for _, item := range items {
+		if s, ok := Score(query, item); ok {
+			matches = append(matches, match{item: item, score: s})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+
+	res := make([]string, 0, len(matches))
+	for _, m := range matches {
+		res = append(res, m.item)
+	}
+
+	return res
+}
+
+// Score returns the score of the query matched against the item, and false
+// if it does not match. Matching characters that are consecutive or at the
+// beginning of a path element or a word score higher.
+func Score(query, item string) (int, bool) {
+	q := []rune(query)
+	it := []rune(item)
+	if !hasUpper(q) {
+		q = []rune(strings.ToLower(query))
+		it = []rune(strings.ToLower(item))
+	}
+	if len(q) == 0 {
+		return 0, true
+	}
+
+	// try every occurrence of the first character and keep the best match
+	best, found := 0, false
+	for start := range it {
+		if it[start] != q[0] {
+			continue
+		}
+		if score, ok := scoreFrom(q, it, start); ok && (!found || score > best) {
+			best, found = score, true
+		}
+	}
+	if !found {
+		return 0, false
+	}
+
+	// prefer the shorter items
+	return best - (len(it) - len(q)), true
+}
+
+// scoreFrom matches the query greedily starting at the index start of the
+// item.
+func scoreFrom(q, it []rune, start int) (int, bool) {
+	var score, qi int
+	last := -1
+	for i := start; i < len(it) && qi < len(q); i++ {
+		if it[i] != q[qi] {
+			continue
+		}
+
+		score += scoreMatch
+		switch {
+		case last >= 0 && i == last+1:
+			score += bonusConsecutive
+		case last >= 0:
+			score -= penaltyGap * (i - last - 1)
+		}
+		if i == 0 || isBoundary(it[i-1]) {
+			score += bonusBoundary
+		}
+
+		last = i
+		qi++
+	}
+
+	return score, qi == len(q)
+}
+
+func hasUpper(rs []rune) bool {
+	for _, r := range rs {
+		if unicode.IsUpper(r) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isBoundary(r rune) bool {
+	switch r {
+	case '/', '.', '-', '_', ' ', ':':
+		return true
+	}
+
+	return unicode.Is(unicode.Symbol, r) || unicode.Is(unicode.Punct, r)
+}
@@ -0,0 +1,47 @@
+package selector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilter(t *testing.T) {
+	items := []string{
+		"github•com/kalbasit/swm",
+		"github•com/kalbasit/dotfiles",
+		"github•com/owner/swarm",
+		"gitlab•com/group/Swm-tools",
+	}
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{"", items},
+		{"swm", []string{"github•com/kalbasit/swm", "gitlab•com/group/Swm-tools", "github•com/owner/swarm"}},
+		{"Swm", []string{"gitlab•com/group/Swm-tools"}},
+		{"kbdot", []string{"github•com/kalbasit/dotfiles"}},
+		{"xyz", nil},
+	}
+	for _, test := range tests {
+		got := Filter(test.query, items)
+		if test.expected == nil {
+			assert.Empty(t, got, test.query)
+			continue
+		}
+		assert.Equal(t, test.expected, got, test.query)
+	}
+}
+
+func TestScore(t *testing.T) {
+	// consecutive characters score higher than scattered ones
+	consecutive, ok := Score("repo", "owner/repo")
+	assert.True(t, ok)
+	scattered, ok := Score("repo", "r-e-p-o")
+	assert.True(t, ok)
+	assert.Greater(t, consecutive, scattered)
+
+	_, ok = Score("repos", "owner/repo")
+	assert.False(t, ok)
+}
@@ -0,0 +1,58 @@
+package selector
+
+import (
+	"strings"
+
+	"github.com/kalbasit/swm/ifaces"
+	"github.com/kalbasit/swm/tools"
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrNoSelection is returned by Select if the user aborted the selection
+	// or the selection did not match any of the items
+	ErrNoSelection = errors.New("nothing was selected")
+
+	// ErrUnknownSelector is returned by New if the selector is not one of the
+	// supported selectors
+	ErrUnknownSelector = errors.New("unknown selector")
+)
+
+// The names of the selectors.
+const (
+	// Auto uses fzf if it's installed and the built-in finder otherwise
+	Auto    = "auto"
+	Builtin = "builtin"
+	Fzf     = "fzf"
+	Skim    = "skim"
+	Rofi    = "rofi"
+	Dmenu   = "dmenu"
+)
+
+// Names returns the names of all the selectors.
+func Names() []string {
+	return []string{Auto, Builtin, Fzf, Skim, Rofi, Dmenu}
+}
+
+// New returns the selector named name, Auto is used if name is empty.
+func New(name string) (ifaces.Selector, error) {
+	switch name {
+	case Auto, "":
+		if _, err := tools.Path(tools.Fzf); err == nil {
+			return &command{tool: tools.Fzf}, nil
+		}
+		return &builtin{}, nil
+	case Builtin:
+		return &builtin{}, nil
+	case Fzf:
+		return &command{tool: tools.Fzf}, nil
+	case Skim:
+		return &command{tool: tools.Sk}, nil
+	case Rofi:
+		return &command{tool: tools.Rofi, args: []string{"-dmenu", "-i", "-p", "swm"}}, nil
+	case Dmenu:
+		return &command{tool: tools.Dmenu, args: []string{"-i", "-p", "swm"}}, nil
+	default:
+		return nil, errors.Wrapf(ErrUnknownSelector, "%q is not one of %s", name, strings.Join(Names(), ", "))
+	}
+}
@@ -0,0 +1,25 @@
+package selector
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	for _, name := range Names() {
+		s, err := New(name)
+		if assert.NoError(t, err, name) {
+			assert.NotNil(t, s, name)
+		}
+	}
+
+	s, err := New(Builtin)
+	if assert.NoError(t, err) {
+		assert.IsType(t, &builtin{}, s)
+	}
+
+	_, err = New("peco")
+	assert.True(t, errors.Is(err, ErrUnknownSelector))
+}
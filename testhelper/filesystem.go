@@ -14,6 +14,15 @@ import (
 var (
 	// gitPath is the PATH to the git binary
 	gitPath string
+
+	// GitIdentity is the environment of the git commits of the fixtures, so
+	// they do not depend on the configuration of the user
+	GitIdentity = []string{
+		"GIT_AUTHOR_NAME=swm",
+		"GIT_AUTHOR_EMAIL=swm@example.com",
+		"GIT_COMMITTER_NAME=swm",
+		"GIT_COMMITTER_EMAIL=swm@example.com",
+	}
 )
 
 func init() {
@@ -85,6 +94,7 @@ func gitInitRepo(p string, verbose bool) error {
 
 	cmd = exec.Command(gitPath, "commit", "--no-verify", "--no-gpg-sign", "--message", "initial import")
 	cmd.Dir = p
+	cmd.Env = append(os.Environ(), GitIdentity...)
 	cmd.Stdout = writer
 	cmd.Stderr = writer
 	if err := cmd.Run(); err != nil {
@@ -0,0 +1,237 @@
+package tmux
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/kalbasit/swm/tools"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	// ErrCommandFailed is returned by a client if tmux reports an error.
+	ErrCommandFailed = errors.New("tmux command failed")
+
+	// errNotConnected is returned if the control-mode connection cannot be
+	// established, usually because the server is not running.
+	errNotConnected = errors.New("not connected to the tmux server")
+)
+
+// Client runs tmux commands on the server of a socket. The commands are
+// given without the flags selecting the socket.
+type Client interface {
+	// Run runs the command and returns its output, without the trailing
+	// newline.
+	Run(args ...string) (string, error)
+
+	// Close disconnects the client from the server.
+	Close() error
+}
+
+// WithClient sets the client running the tmux commands. It defaults to a
+// client talking to the server of the story over one control-mode
+// connection.
+func WithClient(c Client) Option {
+	return func(m *Manager) { m.client = c }
+}
+
+// execClient forks tmux for every command.
+type execClient struct {
+	// flags select the server, see Manager.serverFlags
+	flags []string
+	env   []string
+}
+
+func (c *execClient) Run(args ...string) (string, error) {
+	tmuxPath, err := tools.Path(tools.Tmux)
+	if err != nil {
+		return "", err
+	}
+
+	// the output is parsed, -u keeps tmux from escaping the non-ASCII
+	// characters of the session names
+	cmd := exec.Command(tmuxPath, append(append([]string{"-u"}, c.flags...), args...)...)
+	cmd.Env = c.env
+	out, err := cmd.Output()
+	if err != nil {
+		if ee, ok := err.(*exec.ExitError); ok && len(ee.Stderr) > 0 {
+			return "", errors.Wrapf(ErrCommandFailed, "tmux %s: %s", strings.Join(args, " "), strings.TrimSpace(string(ee.Stderr)))
+		}
+		return "", errors.Wrapf(err, "error running tmux %s", strings.Join(args, " "))
+	}
+
+	return strings.TrimRight(string(out), "\n"), nil
+}
+
+func (c *execClient) Close() error { return nil }
+
+// controlClient runs the commands over one connection to the server, a tmux
+// client in control mode (tmux -C). The control client is attached to a
+// session but it neither receives the output of the panes nor resizes the
+// windows. The commands are forked, with an execClient, while the server is
+// not running.
+type controlClient struct {
+	exec *execClient
+
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+}
+
+// newControlClient returns a client connecting to the server selected by the
+// flags on its first command. The environment is the one of the tmux
+// clients.
+func newControlClient(flags, env []string) *controlClient {
+	return &controlClient{exec: &execClient{flags: flags, env: env}}
+}
+
+func (c *controlClient) Run(args ...string) (string, error) {
+	if c.cmd == nil {
+		if err := c.connect(); err != nil {
+			log.Debug().Err(err).Msg("running the tmux command without a control-mode connection")
+			return c.exec.Run(args...)
+		}
+	}
+
+	quoted := make([]string, 0, len(args))
+	for _, arg := range args {
+		quoted = append(quoted, controlQuote(arg))
+	}
+	if _, err := io.WriteString(c.stdin, strings.Join(quoted, " ")+"\n"); err != nil {
+		c.Close()
+		return "", errors.Wrapf(err, "error sending tmux %s", strings.Join(args, " "))
+	}
+
+	out, ok, err := c.readBlock(true)
+	if err != nil {
+		c.Close()
+		return "", errors.Wrapf(err, "error running tmux %s", strings.Join(args, " "))
+	}
+	if !ok {
+		return "", errors.Wrapf(ErrCommandFailed, "tmux %s: %s", strings.Join(args, " "), out)
+	}
+
+	return out, nil
+}
+
+// connect starts the control client, and waits for it to attach.
+func (c *controlClient) connect() error {
+	tmuxPath, err := tools.Path(tools.Tmux)
+	if err != nil {
+		return err
+	}
+
+	args := append(append([]string{"-u", "-C"}, c.exec.flags...), "attach-session", "-f", "no-output,ignore-size")
+	cmd := exec.Command(tmuxPath, args...)
+	cmd.Env = c.exec.env
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	c.cmd, c.stdin, c.stdout = cmd, stdin, bufio.NewReader(stdout)
+
+	// the commands sent before the client is attached may be run before it,
+	// and the client exits if it's not attached once they're done
+	out, ok, err := c.readBlock(false)
+	if err == nil && !ok {
+		err = errors.Wrap(errNotConnected, out)
+	}
+	if err != nil {
+		c.Close()
+		return err
+	}
+
+	return nil
+}
+
+// readBlock reads the output of the next command, a block between %begin
+// and %end (or %error if the command failed). The blocks of the commands of
+// the client are flagged, the others are the command the client was started
+// with. The notifications are skipped.
+func (c *controlClient) readBlock(fromClient bool) (string, bool, error) {
+	var (
+		lines  []string
+		number string
+		inside bool
+	)
+	for {
+		line, err := c.stdout.ReadString('\n')
+		if err != nil {
+			if err == io.EOF {
+				return "", false, errNotConnected
+			}
+			return "", false, err
+		}
+		line = strings.TrimSuffix(line, "\n")
+
+		if !inside {
+			fields := strings.Fields(line)
+			switch {
+			case len(fields) == 4 && fields[0] == "%begin" && (fields[3] == "1") == fromClient:
+				number, inside = fields[2], true
+			case len(fields) > 0 && fields[0] == "%exit":
+				return "", false, errNotConnected
+			}
+			continue
+		}
+
+		if fields := strings.Fields(line); len(fields) == 4 && fields[2] == number && (fields[0] == "%end" || fields[0] == "%error") {
+			return strings.Join(lines, "\n"), fields[0] == "%end", nil
+		}
+		lines = append(lines, line)
+	}
+}
+
+// Close detaches the control client.
+func (c *controlClient) Close() error {
+	if c.cmd == nil {
+		return nil
+	}
+
+	c.stdin.Close()
+	err := c.cmd.Wait()
+	c.cmd, c.stdin, c.stdout = nil, nil, nil
+
+	return err
+}
+
+// controlQuote quotes the argument for the command parser of tmux.
+func controlQuote(arg string) string {
+	if !strings.ContainsAny(arg, "\n\r") {
+		return "'" + strings.Replace(arg, "'", `'\''`, -1) + "'"
+	}
+
+	// a command is one line, the new lines are escaped within double quotes
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "\n", `\n`, "\r", `\r`).Replace(arg) + `"`
+}
+
+// clientEnv returns the environment of the tmux clients, the current one
+// with the variables of the story and outside of any tmux session.
+func (t *Manager) clientEnv() []string {
+	var res []string
+	if t.story != nil {
+		res = append(res, []string{
+			fmt.Sprintf("SWM_STORY_NAME=%s", t.story.GetName()),
+			fmt.Sprintf("SWM_STORY_BRANCH_NAME=%s", t.story.GetBranchName()),
+		}...)
+	}
+	for _, v := range os.Environ() {
+		if k := strings.Split(v, "=")[0]; k != "SWM_STORY_NAME" && k != "SWM_STORY_BRANCH_NAME" && k != "TMUX" {
+			res = append(res, v)
+		}
+	}
+
+	return res
+}
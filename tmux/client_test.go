@@ -0,0 +1,62 @@
+package tmux
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestControlQuote(t *testing.T) {
+	assert.Equal(t, `'#{window_id} #{pane_id}'`, controlQuote("#{window_id} #{pane_id}"))
+	assert.Equal(t, `'it'\''s $HOME; ~'`, controlQuote("it's $HOME; ~"))
+	assert.Equal(t, `"a\nb \"\$HOME\" \\"`, controlQuote("a\nb \"$HOME\" \\"))
+}
+
+func TestReadBlock(t *testing.T) {
+	client := func(out string) *controlClient {
+		return &controlClient{stdout: bufio.NewReader(strings.NewReader(out))}
+	}
+
+	t.Run("attached", func(t *testing.T) {
+		c := client("%begin 1 263 0\n%end 1 263 0\n%session-changed $0 base\n")
+		out, ok, err := c.readBlock(false)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Empty(t, out)
+	})
+
+	t.Run("no sessions", func(t *testing.T) {
+		c := client("%begin 1 259 0\nno sessions\n%error 1 259 0\n%exit\n")
+		out, ok, err := c.readBlock(false)
+		require.NoError(t, err)
+		assert.False(t, ok)
+		assert.Equal(t, "no sessions", out)
+	})
+
+	t.Run("the output of a command", func(t *testing.T) {
+		c := client("%sessions-changed\n%begin 1 264 0\n%end 1 264 0\n%begin 1 265 1\n@1 %1\n%end 1 264 1\n%end 1 265 1\n")
+		out, ok, err := c.readBlock(true)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, "@1 %1\n%end 1 264 1", out)
+	})
+
+	t.Run("an error", func(t *testing.T) {
+		c := client("%begin 1 266 1\ncan't find session: x\n%error 1 266 1\n")
+		out, ok, err := c.readBlock(true)
+		require.NoError(t, err)
+		assert.False(t, ok)
+		assert.Equal(t, "can't find session: x", out)
+	})
+
+	t.Run("the server exited", func(t *testing.T) {
+		for _, out := range []string{"%exit\n", "%begin 1 267 1\n"} {
+			_, _, err := client(out).readBlock(true)
+			assert.True(t, errors.Is(err, errNotConnected), out)
+		}
+	})
+}
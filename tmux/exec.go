@@ -0,0 +1,218 @@
+package tmux
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bmatcuk/doublestar"
+	"github.com/pkg/errors"
+)
+
+// ExecResult reports the command broadcast by Exec to a session.
+type ExecResult struct {
+	// Session is the name of the session, or of the window of the project in
+	// WindowPerProject.
+	Session string `json:"session"`
+
+	// Project is the import path of the project of the session.
+	Project string `json:"project"`
+
+	// Pane is the pane the command was typed in, or the one that was busy.
+	Pane string `json:"pane,omitempty"`
+
+	// Busy is the command running in the foreground of the pane, the command
+	// was not sent to a busy pane.
+	Busy string `json:"busy,omitempty"`
+}
+
+// ExecOptions are the options of Exec.
+type ExecOptions struct {
+	// Pattern selects the projects by import path, with the syntax of
+	// doublestar (github.com/owner/**). All the projects are selected if
+	// it's empty.
+	Pattern string
+
+	// NewWindow runs the command in a new window of each session, within the
+	// directory of the project, rather than in its active pane. It's a new
+	// pane of the window of the project in WindowPerProject.
+	NewWindow bool
+}
+
+// execPanesFormat are the fields of the panes listed by Exec, separated by
+// tabs.
+var execPanesFormat = strings.Join([]string{
+	"#{session_name}",
+	"#{window_name}",
+	"#{window_index}",
+	"#{pane_index}",
+	"#{pane_id}",
+	"#{pane_pid}",
+	"#{pane_tty}",
+	"#{window_active}",
+	"#{pane_active}",
+}, "\t")
+
+// execPane is a pane listed with execPanesFormat.
+type execPane struct {
+	target string
+	id     string
+	pid    int
+	tty    string
+}
+
+// CommandLine returns the command line running the command with its
+// arguments, they are quoted for the shell if needed. A single argument is
+// the command line itself, such as "make && make install".
+func CommandLine(args []string) string {
+	if len(args) == 1 {
+		return args[0]
+	}
+
+	words := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == "" || strings.IndexFunc(arg, needsQuoting) >= 0 {
+			arg = shellQuote(arg)
+		}
+		words = append(words, arg)
+	}
+
+	return strings.Join(words, " ")
+}
+
+// needsQuoting returns true if the rune has a special meaning for the shell.
+func needsQuoting(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return false
+	}
+
+	return !strings.ContainsRune("_-+=@%:,./", r)
+}
+
+// Exec types the command line in the active pane of every running session of
+// the story, followed by Enter. A pane running a command in the foreground
+// is busy, it's skipped and reported. The results are ordered by session
+// name.
+func (t *Manager) Exec(command string, opts ExecOptions) ([]*ExecResult, error) {
+	if strings.TrimSpace(command) == "" {
+		return nil, errors.New("the command is empty")
+	}
+
+	var results []*ExecResult
+	for name, importPath := range t.runningProjects() {
+		if opts.Pattern != "" {
+			ok, err := doublestar.Match(opts.Pattern, importPath)
+			if err != nil {
+				return nil, errors.Wrapf(err, "error matching the pattern %q", opts.Pattern)
+			}
+			if !ok {
+				continue
+			}
+		}
+		results = append(results, &ExecResult{Session: name, Project: importPath})
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Session < results[j].Session })
+	if len(results) == 0 {
+		return results, nil
+	}
+
+	if opts.NewWindow {
+		for _, r := range results {
+			if err := t.execInNewWindow(r, command); err != nil {
+				return results, err
+			}
+		}
+
+		return results, nil
+	}
+
+	panes, err := t.activePanes()
+	if err != nil {
+		return nil, err
+	}
+	table, err := t.processes.Processes()
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range results {
+		pane, ok := panes[r.Session]
+		if !ok {
+			return results, errors.Errorf("the active pane of %s was not found", r.Session)
+		}
+		r.Pane = pane.target
+		if fg := table.Foreground(pane.tty, pane.pid); fg != nil {
+			r.Busy = fg.CommandLine()
+			continue
+		}
+		if err := t.sendCommand(pane.id, command); err != nil {
+			return results, err
+		}
+	}
+
+	return results, nil
+}
+
+// activePanes returns the active panes of the sessions by name, of the
+// windows of the session of the story in WindowPerProject.
+func (t *Manager) activePanes() (map[string]execPane, error) {
+	out, err := t.client.Run("list-panes", "-a", "-F", execPanesFormat)
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing the panes")
+	}
+
+	panes := make(map[string]execPane)
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 9 || fields[8] != "1" {
+			continue
+		}
+		name := fields[0]
+		if t.windowPerProject() {
+			if fields[0] != t.storySessionName() {
+				continue
+			}
+			name = fields[1]
+		} else if fields[7] != "1" {
+			continue
+		}
+		pid, err := strconv.Atoi(fields[5])
+		if err != nil {
+			return nil, errors.Wrapf(err, "error parsing the pane pid of %q", line)
+		}
+		panes[name] = execPane{
+			target: fields[0] + ":" + fields[2] + "." + fields[3],
+			id:     fields[4],
+			pid:    pid,
+			tty:    fields[6],
+		}
+	}
+
+	return panes, nil
+}
+
+// execInNewWindow types the command in a new window of the session of the
+// result, a new pane of the window of the project in WindowPerProject. The
+// window starts in the directory of the project.
+func (t *Manager) execInNewWindow(r *ExecResult, command string) error {
+	args := []string{"new-window", "-d", "-t", "=" + r.Session + ":"}
+	if t.windowPerProject() {
+		args = []string{"split-window", "-d", "-t", t.windowTarget(r.Session)}
+	}
+	if project, err := t.code.GetProjectByRelativePath(r.Project); err == nil {
+		args = append(args, "-c", project.Path(t.story))
+	}
+	args = append(args, "-P", "-F", "#{session_name}:#{window_index}.#{pane_index}\t#{pane_id}")
+
+	out, err := t.client.Run(args...)
+	if err != nil {
+		return errors.Wrapf(err, "error creating a window in %s", r.Session)
+	}
+	ids := strings.Split(out, "\t")
+	if len(ids) != 2 {
+		return errors.Errorf("unexpected output of tmux creating a window: %q", out)
+	}
+	r.Pane = ids[0]
+
+	return t.sendCommand(ids[1], command)
+}
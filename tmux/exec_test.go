@@ -0,0 +1,123 @@
+package tmux
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"testing"
+
+	"github.com/adrg/xdg"
+	"github.com/kalbasit/swm/code"
+	"github.com/kalbasit/swm/testhelper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExec(t *testing.T) {
+	// create a temporary directory
+	dir, err := ioutil.TempDir("", "swm-test-*")
+	require.NoError(t, err)
+
+	// delete it once we are done here
+	defer func() { os.RemoveAll(dir) }()
+
+	// keep the index of the code within the temporary directory
+	xdg.CacheHome = dir
+	defer xdg.Reload()
+
+	// create the filesystem we want to scan
+	require.NoError(t, testhelper.CreateProjects(dir))
+
+	// create a code
+	c := code.New(dir, regexp.MustCompile("^.snapshots$"))
+	require.NoError(t, c.Scan())
+
+	inspector := &FakeProcessInspector{Procs: []Process{
+		{PID: 100, PPID: 1, PGID: 100, TPGID: 100, TTY: "/dev/pts/1", Comm: "zsh", Args: []string{"-zsh"}},
+		{PID: 200, PPID: 1, PGID: 200, TPGID: 300, TTY: "/dev/pts/2", Comm: "zsh", Args: []string{"-zsh"}},
+		{PID: 300, PPID: 200, PGID: 300, TPGID: 300, TTY: "/dev/pts/2", Comm: "vim", Args: []string{"vim", "go.mod"}},
+	}}
+	outputs := func() map[string]string {
+		return map[string]string{
+			"list-sessions": "repo1\tgithub.com/owner1/repo1\nrepo2\tgithub.com/owner2/repo2\nrepo3\tgithub.com/owner3/repo3",
+			"list-panes -a -F " + execPanesFormat: "repo1\tzsh\t0\t0\t%1\t100\t/dev/pts/1\t1\t1\n" +
+				"repo1\tzsh\t0\t1\t%4\t400\t/dev/pts/4\t1\t0\n" +
+				"repo2\tvim\t1\t0\t%2\t200\t/dev/pts/2\t1\t1\n" +
+				"repo3\tzsh\t0\t0\t%3\t500\t/dev/pts/3\t1\t1",
+		}
+	}
+
+	t.Run("the busy panes are skipped", func(t *testing.T) {
+		f := &FakeClient{Outputs: outputs()}
+		tmx := &Manager{code: c, client: f, processes: inspector}
+
+		results, err := tmx.Exec("go get -u ./...", ExecOptions{Pattern: "github.com/owner[12]/*"})
+		require.NoError(t, err)
+		assert.Equal(t, []*ExecResult{
+			{Session: "repo1", Project: "github.com/owner1/repo1", Pane: "repo1:0.0"},
+			{Session: "repo2", Project: "github.com/owner2/repo2", Pane: "repo2:1.0", Busy: "vim go.mod"},
+		}, results)
+		assert.Contains(t, f.Commands, "send-keys -t %1 go get -u ./... Enter")
+		for _, command := range f.Commands {
+			assert.NotContains(t, command, "send-keys -t %2")
+			assert.NotContains(t, command, "send-keys -t %3")
+		}
+	})
+
+	t.Run("no project matches", func(t *testing.T) {
+		f := &FakeClient{Outputs: outputs()}
+		tmx := &Manager{code: c, client: f, processes: inspector}
+
+		results, err := tmx.Exec("make", ExecOptions{Pattern: "gitlab.com/**"})
+		require.NoError(t, err)
+		assert.Empty(t, results)
+		assert.Equal(t, []string{"list-sessions -F #{session_name}\t#{" + projectOption + "}"}, f.Commands)
+	})
+
+	t.Run("in a new window", func(t *testing.T) {
+		o := outputs()
+		o["new-window"] = "repo3:1.0\t%9"
+		f := &FakeClient{Outputs: o}
+		tmx := &Manager{code: c, client: f, processes: inspector}
+
+		results, err := tmx.Exec("make", ExecOptions{Pattern: "**/repo3", NewWindow: true})
+		require.NoError(t, err)
+		assert.Equal(t, []*ExecResult{{Session: "repo3", Project: "github.com/owner3/repo3", Pane: "repo3:1.0"}}, results)
+		assert.Equal(t, []string{
+			"list-sessions -F #{session_name}\t#{" + projectOption + "}",
+			"new-window -d -t =repo3: -c " + path.Join(dir, "repositories", "github.com/owner3/repo3") + " -P -F #{session_name}:#{window_index}.#{pane_index}\t#{pane_id}",
+			"send-keys -t %9 make Enter",
+		}, f.Commands)
+	})
+
+	t.Run("an empty command", func(t *testing.T) {
+		tmx := &Manager{code: c, client: &FakeClient{}, processes: inspector}
+		_, err := tmx.Exec(" ", ExecOptions{})
+		assert.Error(t, err)
+	})
+}
+
+func TestCommandLine(t *testing.T) {
+	assert.Equal(t, "go get -u ./...", CommandLine([]string{"go", "get", "-u", "./..."}))
+	assert.Equal(t, "make && make install", CommandLine([]string{"make && make install"}))
+	assert.Equal(t, `git commit -m 'fix the build' --author ''`, CommandLine([]string{"git", "commit", "-m", "fix the build", "--author", ""}))
+	assert.Equal(t, `echo 'it'\''s $HOME'`, CommandLine([]string{"echo", "it's $HOME"}))
+}
+
+func TestActivePanesWindowPerProject(t *testing.T) {
+	f := &FakeClient{Outputs: map[string]string{
+		"list-panes -a -F " + execPanesFormat: "swm\tzsh\t0\t0\t%1\t100\t/dev/pts/1\t1\t1\n" +
+			"swm\tgithub" + dotChar + "com/owner1/repo1\t1\t0\t%2\t200\t/dev/pts/2\t0\t1\n" +
+			"swm\tgithub" + dotChar + "com/owner2/repo2\t2\t1\t%3\t300\t/dev/pts/3\t1\t1",
+	}}
+	tmx := &Manager{client: f, strategy: WindowPerProject}
+
+	panes, err := tmx.activePanes()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]execPane{
+		"zsh":                                   {target: "swm:0.0", id: "%1", pid: 100, tty: "/dev/pts/1"},
+		"github" + dotChar + "com/owner1/repo1": {target: "swm:1.0", id: "%2", pid: 200, tty: "/dev/pts/2"},
+		"github" + dotChar + "com/owner2/repo2": {target: "swm:2.1", id: "%3", pid: 300, tty: "/dev/pts/3"},
+	}, panes)
+}
@@ -0,0 +1,81 @@
+package tmux
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FakeProcessInspector is a process inspector for the tests, it returns
+// preset processes.
+type FakeProcessInspector struct {
+	// Procs are returned by Processes, in a table
+	Procs []Process
+
+	// Err is returned by Processes if set
+	Err error
+
+	// Calls is the number of calls to Processes
+	Calls int
+}
+
+func (f *FakeProcessInspector) Processes() (ProcessTable, error) {
+	f.Calls++
+	if f.Err != nil {
+		return nil, f.Err
+	}
+
+	return NewProcessTable(f.Procs), nil
+}
+
+// FakeClient is a tmux client for the tests, it records the commands and
+// returns new ids for the windows and the panes created.
+type FakeClient struct {
+	// Commands are the commands run, with their arguments joined by spaces
+	Commands []string
+
+	// Outputs are returned by the commands, by command (the arguments joined
+	// by spaces) or by name of command
+	Outputs map[string]string
+
+	// Errors are returned by the commands, by command or by name of command
+	Errors map[string]error
+
+	// Closed is set once Close is called
+	Closed bool
+
+	ids int
+}
+
+func (f *FakeClient) Run(args ...string) (string, error) {
+	command := strings.Join(args, " ")
+	f.Commands = append(f.Commands, command)
+	for _, key := range []string{command, args[0]} {
+		if err, ok := f.Errors[key]; ok {
+			return "", err
+		}
+		if out, ok := f.Outputs[key]; ok {
+			return out, nil
+		}
+	}
+
+	switch args[0] {
+	case "new-session", "new-window", "split-window":
+		f.ids++
+		for i, arg := range args {
+			if arg == "-F" {
+				return strings.NewReplacer(
+					"#{window_id}", fmt.Sprintf("@%d", f.ids),
+					"#{pane_id}", fmt.Sprintf("%%%d", f.ids),
+					"#{window_index}", "0",
+				).Replace(args[i+1]), nil
+			}
+		}
+	}
+
+	return "", nil
+}
+
+func (f *FakeClient) Close() error {
+	f.Closed = true
+	return nil
+}
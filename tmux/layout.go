@@ -0,0 +1,212 @@
+package tmux
+
+import (
+	"path"
+	"strings"
+
+	"github.com/kalbasit/swm/ifaces"
+	"github.com/kalbasit/swm/layout"
+	"github.com/kalbasit/swm/manifest"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// projectManifest returns the manifest of the project within the story. The
+// manifest comes from the repository, it's ignored until the user trusted it
+// (swm code trust).
+func (t *Manager) projectManifest(project ifaces.Project) (*manifest.Manifest, error) {
+	m, err := manifest.Load(project.Path(t.story))
+	if err != nil {
+		return nil, err
+	}
+	m.Repository = project.Path(nil)
+
+	trusted, err := m.Trusted()
+	if err != nil {
+		return nil, err
+	}
+	if !trusted {
+		log.Warn().
+			Str("import-path", project.String()).
+			Msgf("ignoring the manifest that is not trusted, review %s and run swm code trust %s", path.Join(project.Path(t.story), manifest.FileName), project.Path(t.story))
+		return &manifest.Manifest{}, nil
+	}
+
+	return m, nil
+}
+
+// layoutFor returns the layout of the project. The layout preferred by the
+// manifest of the project is used unless the config has a rule for this
+// project specifically.
+func (t *Manager) layoutFor(project ifaces.Project, m *manifest.Manifest) (layout.Layout, error) {
+	c := t.layouts
+	if c == nil {
+		c = &layout.Config{}
+	}
+
+	if !c.HasProjectRule(project.String()) {
+		switch {
+		case m.Layout != nil:
+			return *m.Layout, nil
+		case m.LayoutName != "":
+			return c.Get(m.LayoutName)
+		}
+	}
+
+	return c.For(project.String())
+}
+
+// newSession creates the session with the windows and the panes of the
+// layout, the directories of the layout are relative to projectPath. The
+// environment variables, formatted as KEY=value, are exported into the
+// session.
+func (t *Manager) newSession(sessionName, projectPath string, l layout.Layout, env []string) error {
+	_, err := t.newWindows(sessionName, true, projectPath, l, env, env)
+
+	return err
+}
+
+// newWindows creates the windows and the panes of the layout in the session,
+// the session is created with the first window if create is true. The
+// environment variables are given to the windows and the panes, the session
+// variables are exported into the new session. It returns the ids of the
+// windows.
+func (t *Manager) newWindows(sessionName string, create bool, projectPath string, l layout.Layout, env, sessionEnv []string) ([]string, error) {
+	if err := l.Validate(); err != nil {
+		return nil, err
+	}
+
+	dir := func(p string) string {
+		if path.IsAbs(p) {
+			return p
+		}
+		return path.Join(projectPath, p)
+	}
+
+	var (
+		focusedWindow string
+		windowIDs     []string
+	)
+	for i, w := range l.Windows {
+		var args []string
+		if i == 0 && create {
+			args = append(args, "new-session", "-d", "-s", sessionName)
+		} else {
+			args = append(args, "new-window", "-d", "-t", sessionName+":")
+		}
+		args = append(args, "-c", dir(w.Dir), "-P", "-F", "#{window_id} #{pane_id}")
+		args = append(args, envArgs(env)...)
+		if w.Name != "" {
+			args = append(args, "-n", w.Name)
+		}
+		out, err := t.client.Run(args...)
+		if err != nil {
+			return nil, err
+		}
+		ids := strings.Fields(out)
+		if len(ids) != 2 {
+			return nil, errors.Errorf("unexpected output of tmux creating a window: %q", out)
+		}
+		windowID, paneID := ids[0], ids[1]
+		windowIDs = append(windowIDs, windowID)
+
+		if i == 0 && create {
+			// set the environment of the session before creating the other
+			// windows, the windows created later by the user inherit it.
+			if err := t.setSessionEnv(sessionName, sessionEnv); err != nil {
+				return nil, err
+			}
+			if err := t.setStatusOptions(); err != nil {
+				return nil, err
+			}
+		}
+
+		if w.Focus || focusedWindow == "" {
+			focusedWindow = windowID
+		}
+		if err := t.sendCommand(paneID, w.Command); err != nil {
+			return nil, err
+		}
+
+		var focusedPane string
+		for _, p := range w.Panes {
+			paneDir := p.Dir
+			if paneDir == "" {
+				paneDir = w.Dir
+			}
+			args := []string{"split-window", "-d", "-t", paneID, "-c", dir(paneDir), "-P", "-F", "#{pane_id}"}
+			args = append(args, envArgs(env)...)
+			if p.Split == layout.SplitHorizontal {
+				args = append(args, "-h")
+			} else {
+				args = append(args, "-v")
+			}
+			if p.Size != "" {
+				args = append(args, "-l", p.Size)
+			}
+			if paneID, err = t.client.Run(args...); err != nil {
+				return nil, err
+			}
+			if p.Focus {
+				focusedPane = paneID
+			}
+			if err := t.sendCommand(paneID, p.Command); err != nil {
+				return nil, err
+			}
+		}
+		if focusedPane != "" {
+			if _, err := t.client.Run("select-pane", "-t", focusedPane); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if _, err := t.client.Run("select-window", "-t", focusedWindow); err != nil {
+		return nil, err
+	}
+
+	return windowIDs, nil
+}
+
+// setSessionEnv sets the environment of the story and the given variables,
+// formatted as KEY=value, in the environment of the session.
+func (t *Manager) setSessionEnv(sessionName string, env []string) error {
+	var sessionEnv [][]string
+	if t.story != nil {
+		sessionEnv = append(sessionEnv,
+			[]string{"SWM_STORY_NAME", t.story.GetName()},
+			[]string{"SWM_STORY_BRANCH_NAME", t.story.GetBranchName()},
+		)
+	}
+	for _, e := range env {
+		sessionEnv = append(sessionEnv, strings.SplitN(e, "=", 2))
+	}
+	for _, kv := range sessionEnv {
+		if _, err := t.client.Run("set-environment", "-t", sessionName, kv[0], kv[1]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// envArgs returns the arguments setting the environment of a new window or
+// pane. They are only given if needed as they require tmux 3.2.
+func envArgs(env []string) []string {
+	var args []string
+	for _, e := range env {
+		args = append(args, "-e", e)
+	}
+
+	return args
+}
+
+// sendCommand types the command in the pane, if any.
+func (t *Manager) sendCommand(paneID, command string) error {
+	if command == "" {
+		return nil
+	}
+	_, err := t.client.Run("send-keys", "-t", paneID, command, "Enter")
+
+	return err
+}
@@ -0,0 +1,175 @@
+package tmux
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"testing"
+
+	"github.com/adrg/xdg"
+	"github.com/kalbasit/swm/code"
+	"github.com/kalbasit/swm/ifaces"
+	"github.com/kalbasit/swm/layout"
+	"github.com/kalbasit/swm/manifest"
+	"github.com/kalbasit/swm/project"
+	"github.com/kalbasit/swm/story"
+	"github.com/kalbasit/swm/testhelper"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSession(t *testing.T) {
+	t.Run("the default layout", func(t *testing.T) {
+		f := &FakeClient{}
+		tmx := &Manager{client: f}
+		require.NoError(t, tmx.newSession("session", "/code/project", layout.Default(), nil))
+		assert.Equal(t, []string{
+			"new-session -d -s session -c /code/project -P -F #{window_id} #{pane_id}",
+			"send-keys -t %1 type vim_ready &>/dev/null && vim_ready; clear; vim Enter",
+			"new-window -d -t session: -c /code/project -P -F #{window_id} #{pane_id}",
+			"select-window -t @2",
+		}, f.Commands)
+	})
+
+	t.Run("windows and panes", func(t *testing.T) {
+		s, err := story.New("STORY-123", "")
+		require.NoError(t, err)
+
+		f := &FakeClient{}
+		tmx := &Manager{story: s, client: f}
+		require.NoError(t, tmx.newSession("session", "/code/project", layout.Layout{
+			Windows: []layout.Window{
+				{Name: "editor", Command: "vim", Focus: true},
+				{
+					Name: "web",
+					Dir:  "web",
+					Panes: []layout.Pane{
+						{Command: "yarn start", Split: layout.SplitHorizontal, Size: "30%", Focus: true},
+						{Dir: "/tmp", Command: "tail -f log"},
+					},
+				},
+			},
+		}, nil))
+		assert.Equal(t, []string{
+			"new-session -d -s session -c /code/project -P -F #{window_id} #{pane_id} -n editor",
+			"set-environment -t session SWM_STORY_NAME STORY-123",
+			"set-environment -t session SWM_STORY_BRANCH_NAME STORY-123",
+			"set-option -g @swm-story STORY-123",
+			"set-option -g @swm-story-branch STORY-123",
+			"send-keys -t %1 vim Enter",
+			"new-window -d -t session: -c /code/project/web -P -F #{window_id} #{pane_id} -n web",
+			"split-window -d -t %2 -c /code/project/web -P -F #{pane_id} -h -l 30%",
+			"send-keys -t %3 yarn start Enter",
+			"split-window -d -t %3 -c /tmp -P -F #{pane_id} -v",
+			"send-keys -t %4 tail -f log Enter",
+			"select-pane -t %3",
+			"select-window -t @1",
+		}, f.Commands)
+	})
+
+	t.Run("the environment of the manifest", func(t *testing.T) {
+		f := &FakeClient{}
+		tmx := &Manager{client: f}
+		require.NoError(t, tmx.newSession("session", "/code/project", layout.Layout{
+			Windows: []layout.Window{{Panes: []layout.Pane{{}}}, {}},
+		}, []string{"GOFLAGS=-mod=vendor", "EMPTY="}))
+		assert.Equal(t, []string{
+			"new-session -d -s session -c /code/project -P -F #{window_id} #{pane_id} -e GOFLAGS=-mod=vendor -e EMPTY=",
+			"set-environment -t session GOFLAGS -mod=vendor",
+			"set-environment -t session EMPTY ",
+			"split-window -d -t %1 -c /code/project -P -F #{pane_id} -e GOFLAGS=-mod=vendor -e EMPTY= -v",
+			"new-window -d -t session: -c /code/project -P -F #{window_id} #{pane_id} -e GOFLAGS=-mod=vendor -e EMPTY=",
+			"select-window -t @1",
+		}, f.Commands)
+	})
+
+	t.Run("an invalid layout", func(t *testing.T) {
+		f := &FakeClient{}
+		tmx := &Manager{client: f}
+		assert.Error(t, tmx.newSession("session", "/code/project", layout.Layout{}, nil))
+		assert.Empty(t, f.Commands)
+	})
+}
+
+func TestLayoutFor(t *testing.T) {
+	goService := layout.Layout{Windows: []layout.Window{{Command: "vim"}, {Command: "make watch"}}}
+	inline := layout.Layout{Windows: []layout.Window{{Command: "yarn start"}}}
+	c := &layout.Config{
+		Layouts: map[string]layout.Layout{"go-service": goService},
+		Rules: []layout.Rule{
+			{Match: "github.com/owner2/repo2", Layout: "go-service"},
+			{Match: "github.com/**", Layout: "go-service"},
+		},
+	}
+	prj1 := project.New(nil, "github.com/owner1/repo1")
+	prj2 := project.New(nil, "github.com/owner2/repo2")
+
+	for name, test := range map[string]struct {
+		layouts  *layout.Config
+		project  ifaces.Project
+		manifest *manifest.Manifest
+		want     layout.Layout
+	}{
+		"no config":                        {nil, prj1, &manifest.Manifest{}, layout.Default()},
+		"a pattern rule":                   {c, prj1, &manifest.Manifest{}, goService},
+		"the manifest over a pattern":      {c, prj1, &manifest.Manifest{Layout: &inline}, inline},
+		"a named layout of the manifest":   {c, prj1, &manifest.Manifest{LayoutName: "default"}, layout.Default()},
+		"a project rule over the manifest": {c, prj2, &manifest.Manifest{Layout: &inline}, goService},
+		"the manifest without a config":    {nil, prj1, &manifest.Manifest{Layout: &inline}, inline},
+	} {
+		t.Run(name, func(t *testing.T) {
+			tmx := &Manager{layouts: test.layouts}
+			l, err := tmx.layoutFor(test.project, test.manifest)
+			require.NoError(t, err)
+			assert.Equal(t, test.want, l)
+		})
+	}
+
+	t.Run("an unknown layout named by the manifest", func(t *testing.T) {
+		tmx := &Manager{layouts: c}
+		_, err := tmx.layoutFor(prj1, &manifest.Manifest{LayoutName: "unknown"})
+		assert.True(t, errors.Is(err, layout.ErrUnknownLayout))
+	})
+}
+
+func TestProjectManifest(t *testing.T) {
+	// create a temporary directory
+	dir, err := ioutil.TempDir("", "swm-test-*")
+	require.NoError(t, err)
+
+	// delete it once we are done here
+	defer func() { os.RemoveAll(dir) }()
+
+	// keep the index and the trusted manifests within the temporary directory
+	xdg.CacheHome = dir
+	xdg.DataHome = dir
+	defer xdg.Reload()
+
+	// create the filesystem we want to scan
+	require.NoError(t, testhelper.CreateProjects(dir))
+
+	// create a code
+	c := code.New(dir, regexp.MustCompile("^.snapshots$"))
+	require.NoError(t, c.Scan())
+	prj, err := c.GetProjectByRelativePath("github.com/owner1/repo1")
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(path.Join(prj.Path(nil), manifest.FileName), []byte("env:\n  - LD_PRELOAD=./evil.so\nlayout: go-service\n"), 0644))
+
+	tmx := &Manager{code: c}
+
+	// the manifest is ignored until it is trusted
+	m, err := tmx.projectManifest(prj)
+	require.NoError(t, err)
+	assert.Equal(t, &manifest.Manifest{}, m)
+
+	m, err = manifest.Load(prj.Path(nil))
+	require.NoError(t, err)
+	require.NoError(t, m.Trust())
+
+	m, err = tmx.projectManifest(prj)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"LD_PRELOAD=./evil.so"}, m.Env)
+	assert.Equal(t, "go-service", m.LayoutName)
+}
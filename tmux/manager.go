@@ -2,16 +2,17 @@ package tmux
 
 import (
 	"fmt"
-	"io"
 	"os"
 	"os/exec"
-	"path"
-	"regexp"
+	"sort"
 	"strings"
 	"syscall"
 
 	"github.com/kalbasit/swm/ifaces"
+	"github.com/kalbasit/swm/layout"
+	"github.com/kalbasit/swm/selector"
 	"github.com/kalbasit/swm/story"
+	"github.com/kalbasit/swm/tools"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 )
@@ -23,54 +24,101 @@ const (
 
 var (
 	// ErrProjectNotFoundForGivenSessionName is returned by SwitchClient if the
-	// selected session (via fzf currently) was not found. This usually means
-	// that fzf output was not one of the input.
+	// selected session was not found. This usually means that the output of
+	// the selector was not one of the input.
 	ErrProjectNotFoundForGivenSessionName = errors.New("project not found for the given session name")
 
-	// ErrVimSessionFound is returned by KillServer(closeVim bool) if a vim was
-	// found running on the server and closeVim is false
-	ErrVimSessionFound = errors.New("vim was found, cannot exit server to avoid data loss")
+	// ErrNoProjectMatch is returned by SwitchClient if no project matches the
+	// given query.
+	ErrNoProjectMatch = errors.New("no project matches the query")
 
-	// tmuxPath is the PATH to the tmux executable
-	tmuxPath string
+	// ErrAmbiguousProject is returned by SwitchClient if the given query
+	// matches more than one project equally well.
+	ErrAmbiguousProject = errors.New("the query matches more than one project")
 
-	// fzfPath is the PATH to the fzf executable
-	fzfPath string
+	// ErrVimSessionFound is returned by KillServer if a vim was found running
+	// on the server.
+	//
+	// Deprecated: vim is one of the protected processes, use
+	// ErrProtectedProcessFound.
+	ErrVimSessionFound = ErrProtectedProcessFound
+)
 
-	// path path to the ps tool
-	psPath string
+// Manager represents a TMUX manager
+type Manager struct {
+	code     ifaces.Code
+	story    ifaces.Story
+	selector ifaces.Selector
+	layouts  *layout.Config
+	client   Client
 
-	psGrepRegexp = regexp.MustCompile(`(?m)^(?:[^TXZ ])+ +(?:((?:\S)+/))?g?(n?vim?x?)$`)
-)
+	restoreCommands []string
+	restorePrompt   func(*Snapshot) (bool, error)
 
-func init() {
-	var err error
+	protectedProcesses []ProtectedProcess
+	processes          ProcessInspector
 
-	tmuxPath, err = exec.LookPath("tmux")
-	if err != nil {
-		log.Fatal().Msgf("error looking up the tmux executable, is it installed? %s", err)
-	}
+	mux      ifaces.Multiplexer
+	strategy string
 
-	fzfPath, err = exec.LookPath("fzf")
-	if err != nil {
-		log.Fatal().Msgf("error looking up the fzf executable, is it installed? %s", err)
-	}
+	naming       string
+	aliases      map[string]string
+	sessionNames map[string]string
 
-	psPath, err = exec.LookPath("ps")
-	if err != nil {
-		log.Fatal().Msgf("error looking up the ps executable, is it installed? %s", err)
-	}
+	socketDir  string
+	configFile string
 }
 
-// Manager represents a TMUX manager
-type Manager struct {
-	code  ifaces.Code
-	story ifaces.Story
+// Option configures a manager returned by New.
+type Option func(*Manager)
+
+// WithSelector sets the selector used to pick a project. It defaults to the
+// auto selector.
+func WithSelector(s ifaces.Selector) Option {
+	return func(m *Manager) { m.selector = s }
+}
+
+// WithMultiplexer sets the multiplexer running the sessions of the projects.
+// The manager runs them on tmux by default. The saved sessions, the layouts
+// and the vim sessions are only used by tmux.
+func WithMultiplexer(mux ifaces.Multiplexer) Option {
+	return func(m *Manager) { m.mux = mux }
+}
+
+// WithLayouts sets the layouts of the new sessions. The builtin layout is
+// used by default.
+func WithLayouts(c *layout.Config) Option {
+	return func(m *Manager) { m.layouts = c }
 }
 
 // New returns a new tmux manager
-func New(c ifaces.Code, storyName string) (*Manager, error) {
+func New(c ifaces.Code, storyName string, opts ...Option) (*Manager, error) {
 	m := &Manager{code: c}
+	for _, opt := range opts {
+		opt(m)
+	}
+	if m.selector == nil {
+		sel, err := selector.New(selector.Auto)
+		if err != nil {
+			return nil, err
+		}
+		m.selector = sel
+	}
+	if m.processes == nil {
+		m.processes = defaultProcessInspector()
+	}
+	if m.protectedProcesses == nil {
+		m.protectedProcesses = DefaultProtectedProcesses()
+	}
+	if err := compileProtectedProcesses(m.protectedProcesses); err != nil {
+		return nil, err
+	}
+	if err := validateStrategy(m.strategy); err != nil {
+		return nil, err
+	}
+	if err := validateNaming(m.naming); err != nil {
+		return nil, err
+	}
 
 	if storyName != "" {
 		s, err := story.Load(storyName)
@@ -79,153 +127,365 @@ func New(c ifaces.Code, storyName string) (*Manager, error) {
 		}
 		m.story = s
 	}
+	if err := m.ensureSocketDir(); err != nil {
+		return nil, errors.Wrap(err, "error creating the socket directory")
+	}
+	if m.client == nil {
+		m.client = newControlClient(m.serverFlags(), m.clientEnv())
+	}
 
 	return m, nil
 }
 
-func (t *Manager) KillServer(closeVim bool) error {
-	// find out if we have any running vim session and if we do, act on closeVim
-	targets, err := t.getTargetsRunningVim()
+// KillServer kills the sessions of the story, with the tmux server of the
+// story unless another multiplexer runs them.
+func (t *Manager) KillServer(graceful bool) error {
+	return t.multiplexer().Kill(graceful)
+}
+
+// Name returns the name of the multiplexer.
+func (t *Manager) Name() string { return "tmux" }
+
+// Kill kills the tmux server of the story. It refuses to kill the server if a
+// protected process is running, unless graceful is true in which case the
+// protected processes are asked to exit first. The sessions are saved before
+// the server is killed.
+func (t *Manager) Kill(graceful bool) error {
+	// find out if we have any protected process running and act on graceful
+	panes, err := t.getBusyPanes(t.protectedProcesses)
 	if err != nil {
 		return err
 	}
-	if len(targets) > 0 {
-		if !closeVim {
-			return ErrVimSessionFound
+	if len(panes) > 0 && !graceful {
+		reports := make([]string, 0, len(panes))
+		for _, pane := range panes {
+			reports = append(reports, pane.String())
 		}
-		// ask vim to exit
-		if err := t.VimExit(); err != nil {
+		return errors.Wrapf(ErrProtectedProcessFound, "running in %s", strings.Join(reports, ", "))
+	}
+	// save the sessions to be able to restore them
+	if len(t.runningSessions()) > 0 {
+		if _, err := t.Save(); err != nil {
+			return errors.Wrap(err, "error saving the sessions")
+		}
+	}
+	if len(panes) > 0 {
+		// ask the protected processes to exit
+		if err := t.exitProcesses(panes); err != nil {
 			return err
 		}
 	}
 
-	return exec.Command(tmuxPath, "-L", t.socketName(), "kill-server").Run()
+	_, err = t.client.Run("kill-server")
+
+	return err
+}
+
+// Close disconnects the manager from the tmux server, and from the
+// multiplexer running the sessions.
+func (t *Manager) Close() error {
+	if mux := t.multiplexer(); mux != ifaces.Multiplexer(t) {
+		if err := mux.Close(); err != nil {
+			log.Debug().Err(err).Msgf("error closing %s", mux.Name())
+		}
+	}
+
+	return t.client.Close()
+}
+
+// multiplexer returns the multiplexer running the sessions, the manager
+// itself unless another one was set.
+func (t *Manager) multiplexer() ifaces.Multiplexer {
+	if t.mux == nil {
+		return t
+	}
+
+	return t.mux
 }
 
 // socketName returns the session name
 func (t *Manager) socketName() string {
 	if t.story != nil {
-		return strings.Replace(fmt.Sprintf("swm-%s", t.story.GetName()), "/", "_", -1)
+		return socketNameFor(t.story.GetName())
+	}
+
+	return socketNameFor("")
+}
+
+// socketNameFor returns the name of the socket of the tmux server of the
+// story, the server outside of any story if the name is empty.
+func socketNameFor(storyName string) string {
+	if storyName != "" {
+		return strings.Replace(fmt.Sprintf("swm-%s", storyName), "/", "_", -1)
 	}
 
 	return "swm"
 }
 
-// withFilter filters input using fzf
-func (t *Manager) withFilter(input func(in io.WriteCloser)) (string, error) {
-	shell := os.Getenv("SHELL")
-	if shell == "" {
-		shell = "sh"
-	}
-	cmd := exec.Command(shell, "-c", fzfPath)
-	cmd.Stderr = os.Stderr
-	in, _ := cmd.StdinPipe()
-	go func() {
-		input(in)
-		in.Close()
-	}()
-	result, err := cmd.Output()
+// SwitchClient switches the TMUX to a different client. The project is
+// resolved from the query if given, otherwise the user selects it. The
+// session is started, along with the server of the story, if needed. From
+// the tmux server of another story, the terminal is attached to the server
+// of this story.
+func (t *Manager) SwitchClient(query string, killPane bool) error {
+	mux := t.multiplexer()
+
+	// select the project
+	var (
+		project ifaces.Project
+		err     error
+	)
+	if query != "" {
+		_, project, err = t.resolveProject(query)
+	} else {
+		_, project, err = t.selectProject()
+	}
+	if err != nil {
+		return err
+	}
+	t.recordVisit(project)
+	// make sure the project exists on disk
+	if t.story != nil {
+		if err := project.CreateStory(t.story); err != nil {
+			return err
+		}
+	}
+	// offer to restore the saved sessions when the server is started
+	if mux == ifaces.Multiplexer(t) {
+		if err := t.offerRestore(); err != nil {
+			log.Warn().Err(err).Msg("error restoring the saved sessions")
+		}
+	}
+
+	sessionName, err := mux.EnsureSession(project)
+	if err != nil {
+		return err
+	}
+
+	return mux.Attach(sessionName, killPane)
+}
+
+// SessionName returns the name of the tmux session of the project, given by
+// the session naming and the aliases.
+func (t *Manager) SessionName(p ifaces.Project) string {
+	return t.sessionNameOf(p.String())
+}
+
+// EnsureSession starts the tmux session of the project, unless it's already
+// running, and returns its name. The windows of the session are described by
+// the layout of the project. In WindowPerProject, the window of the project
+// is created in the session of the story and its name is returned.
+//
+// The running session of the project is found by its project option, so it
+// is used whatever its name. A new session is numbered if its name is used
+// by the session of another project.
+func (t *Manager) EnsureSession(project ifaces.Project) (string, error) {
+	if t.windowPerProject() {
+		return t.ensureWindow(project)
+	}
+
+	sessionName, running := claimName(t.SessionName(project), project.String(), t.runningProjects())
+	if running {
+		return sessionName, nil
+	}
+
+	// session does not exist, we should start it
+	m, err := t.projectManifest(project)
 	if err != nil {
 		return "", err
 	}
-	res := strings.Split(string(result), "\n")
-	if res[len(res)-1] == "" {
-		res = res[0 : len(res)-1]
+	l, err := t.layoutFor(project, m)
+	if err != nil {
+		return "", err
+	}
+	l = t.reopenVimSession(l, project.String())
+	if err := t.newSession(sessionName, project.Path(t.story), l, m.Env); err != nil {
+		return "", errors.Wrap(err, "error creating the tmux session")
+	}
+	if _, err := t.client.Run("set-option", "-t", "="+sessionName+":", projectOption, project.String()); err != nil {
+		return "", err
+	}
+
+	return sessionName, nil
+}
+
+// Attach attaches the terminal to the tmux session, see attach. In
+// WindowPerProject, the window of the project is selected and the terminal
+// is attached to the session of the story.
+func (t *Manager) Attach(sessionName string, killPane bool) error {
+	tmuxPath, err := tools.Path(tools.Tmux)
+	if err != nil {
+		return err
+	}
+	if t.windowPerProject() {
+		if _, err := t.client.Run("select-window", "-t", t.windowTarget(sessionName)); err != nil {
+			return err
+		}
+		sessionName = t.storySessionName()
+	}
+	// the client is attached with tmux itself
+	if err := t.client.Close(); err != nil {
+		log.Debug().Err(err).Msg("error closing the tmux client")
+	}
+
+	return t.attach(tmuxPath, sessionName, killPane)
+}
+
+// SetEnv sets the variables in the environment of the tmux session.
+func (t *Manager) SetEnv(sessionName string, env []string) error {
+	return t.setSessionEnv(sessionName, env)
+}
+
+// SendKeys sends the keys to the target, a session or one of its panes, one
+// key at a time.
+func (t *Manager) SendKeys(target string, keys ...string) error {
+	for _, key := range keys {
+		if _, err := t.client.Run("send-keys", "-t", target, key); err != nil {
+			return errors.Wrapf(err, "error sending the keys to %s", target)
+		}
+	}
+
+	return nil
+}
+
+// attach attaches the terminal to the session. Within tmux, the client
+// switches to the session if it's on the same server, otherwise the client
+// is replaced by a client attached to the server of the session.
+func (t *Manager) attach(tmuxPath, sessionName string, killPane bool) error {
+	tmuxSocketPath := os.Getenv("TMUX")
+	if tmuxSocketPath == "" {
+		// NOTE: the following Exec calls kernel's execve, which means that this will
+		// never return and the current swm binary will be replaced by tmux. This is
+		// precisely what we want as there is no sence in keeping swm running after
+		// attaching to a tmux session.
+		args := append(append([]string{"tmux"}, t.serverFlags()...), "attach", "-t"+sessionName)
+		return syscall.Exec(tmuxPath, args, os.Environ())
+	}
+
+	// the current server is given by the path of its socket
+	currentSocketPath := strings.Split(tmuxSocketPath, ",")[0]
+	// kill the pane once attached
+	if killPane {
+		defer func() {
+			exec.Command(tmuxPath, "-S", currentSocketPath, "kill-pane").Run()
+		}()
+	}
+	if currentSocketPath == t.socketPath() {
+		return exec.Command(tmuxPath, "-S", currentSocketPath, "switch-client", "-t", sessionName).Run()
 	}
-	return res[0], nil
+
+	// the session is on the server of another story, the client detaches and
+	// its terminal runs a client attached to the other server
+	return exec.Command(tmuxPath, "-S", currentSocketPath, "detach-client", "-E", attachCommand(tmuxPath, t.serverFlags(), sessionName)).Run()
+}
+
+// attachCommand returns the shell command attaching the terminal to the
+// session on the server selected by the flags.
+func attachCommand(tmuxPath string, flags []string, sessionName string) string {
+	args := []string{"exec", shellQuote(tmuxPath)}
+	for _, flag := range flags {
+		args = append(args, shellQuote(flag))
+	}
+	args = append(args, "attach-session", "-t", shellQuote("="+sessionName))
+
+	return strings.Join(args, " ")
 }
 
-// SwitchClient switches the TMUX to a different client
-func (t *Manager) SwitchClient(killPane bool) error {
-	// get all the sessions
+// selectProject lets the user select a project and returns its session name
+// along with the project.
+func (t *Manager) selectProject() (string, ifaces.Project, error) {
 	sessionNameProjects, err := t.getSessionNameProjects()
 	if err != nil {
-		return err
+		return "", nil, err
 	}
-	// select the session using fzf
-	sessionName, err := t.withFilter(func(stdin io.WriteCloser) {
-		for name := range sessionNameProjects {
-			io.WriteString(stdin, name)
-			io.WriteString(stdin, "\n")
+
+	// list the running sessions first, and mark them
+	running := t.runningSessionNames()
+	names := t.rankSessionNames(sessionNameProjects, running, t.histories())
+	for i, name := range names {
+		if running[name] {
+			names[i] = runningMark + name
 		}
-	})
+	}
+
+	sessionName, err := t.selector.Select(names)
 	if err != nil {
-		return err
+		return "", nil, err
 	}
+	sessionName = strings.TrimPrefix(sessionName, runningMark)
+
 	// get the project for the selected name
 	project, ok := sessionNameProjects[sessionName]
 	if !ok {
-		return ErrProjectNotFoundForGivenSessionName
+		return "", nil, ErrProjectNotFoundForGivenSessionName
 	}
-	// make sure the project exists on disk
-	if t.story != nil {
-		if err := project.CreateStory(t.story); err != nil {
-			return err
-		}
+
+	return sessionName, project, nil
+}
+
+// resolveProject returns the session name and the project matching the
+// query. The query is, in order of preference, the import path of the
+// project, a unique suffix of it made of whole path elements (owner/repo)
+// or a fuzzy query. The fuzzy query must have one best match.
+func (t *Manager) resolveProject(query string) (string, ifaces.Project, error) {
+	sessionNameProjects, err := t.getSessionNameProjects()
+	if err != nil {
+		return "", nil, err
+	}
+
+	projects := make(map[string]ifaces.Project, len(sessionNameProjects))
+	importPaths := make([]string, 0, len(sessionNameProjects))
+	for _, prj := range sessionNameProjects {
+		projects[prj.String()] = prj
+		importPaths = append(importPaths, prj.String())
+	}
+	sort.Strings(importPaths)
+
+	found := func(importPath string) (string, ifaces.Project, error) {
+		return t.multiplexer().SessionName(projects[importPath]), projects[importPath], nil
+	}
+
+	// the import path
+	if _, ok := projects[query]; ok {
+		return found(query)
 	}
-	// run tmux has-session -t sessionName to check if session already exists
-	if err := exec.Command(tmuxPath, "-L", t.socketName(), "has-session", "-t="+sessionName).Run(); err != nil {
-		// session does not exist, we should start it
-		var allArguments [][]string
-		allArguments = append(allArguments, [][]string{
-			// start the session
-			{"-L", t.socketName(), "new-session", "-c", project.Path(t.story), "-d", "-s", sessionName},
-			// start a new shell on window 1
-			{"-L", t.socketName(), "new-window", "-c", project.Path(t.story), "-t", sessionName + ":1"},
-			// start vim in the first window
-			{"-L", t.socketName(), "send-keys", "-t", sessionName + ":0", "type vim_ready &>/dev/null && vim_ready; clear; vim", "Enter"},
-		}...)
-
-		if t.story != nil {
-			allArguments = append(allArguments, [][]string{
-				// set the active story name
-				{"-L", t.socketName(), "set-environment", "-t", sessionName, "SWM_STORY_NAME", t.story.GetName()},
-				// set the branch name
-				{"-L", t.socketName(), "set-environment", "-t", sessionName, "SWM_STORY_BRANCH_NAME", t.story.GetBranchName()},
-			}...)
-		}
 
-		for _, args := range allArguments {
-			cmd := exec.Command(tmuxPath, args...)
-			cmd.Dir = project.Path(t.story)
-			// set the environment to current environment, change only ACTIVE_PROFILE, ACTIVE_STORY  and GOPATH
-			cmd.Env = func() []string {
-				var res []string
-				if t.story != nil {
-					res = append(res, []string{
-						fmt.Sprintf("SWM_STORY_NAME=%s", t.story.GetName()),
-						fmt.Sprintf("SWM_STORY_BRANCH_NAME=%s", t.story.GetBranchName()),
-					}...)
-				}
-				for _, v := range os.Environ() {
-					if k := strings.Split(v, "=")[0]; k != "SWM_STORY_NAME" && k != "TMUX" {
-						res = append(res, v)
-					}
-				}
-
-				return res
-			}()
-			// run the command now
-			if err := cmd.Run(); err != nil {
-				log.Fatal().Strs("args", args).Err(err).Msg("error running the tmux comand")
-			}
+	// a suffix of the import path
+	var suffixed []string
+	for _, ip := range importPaths {
+		if strings.HasSuffix(ip, "/"+strings.Trim(query, "/")) {
+			suffixed = append(suffixed, ip)
 		}
 	}
-	// attach the session now
-	if tmuxSocketPath := os.Getenv("TMUX"); tmuxSocketPath != "" {
-		// kill the pane once attached
-		if killPane {
-			defer func() {
-				exec.Command(tmuxPath, "-L", strings.Split(path.Base(tmuxSocketPath), ",")[0], "kill-pane").Run()
-			}()
+	switch len(suffixed) {
+	case 0:
+	case 1:
+		return found(suffixed[0])
+	default:
+		return "", nil, errors.Wrapf(ErrAmbiguousProject, "%q matches %s", query, strings.Join(suffixed, ", "))
+	}
+
+	// a fuzzy match
+	matches := selector.Filter(query, importPaths)
+	switch len(matches) {
+	case 0:
+		return "", nil, errors.Wrapf(ErrNoProjectMatch, "%q", query)
+	case 1:
+		return found(matches[0])
+	}
+	best, _ := selector.Score(query, matches[0])
+	var candidates []string
+	for _, m := range matches {
+		if s, _ := selector.Score(query, m); s < best {
+			break
 		}
-		return exec.Command(tmuxPath, "-L", t.socketName(), "switch-client", "-t", sessionName).Run()
+		candidates = append(candidates, m)
+	}
+	if len(candidates) > 1 {
+		return "", nil, errors.Wrapf(ErrAmbiguousProject, "%q matches %s", query, strings.Join(candidates, ", "))
 	}
-	// NOTE: the following Exec calls kernel's execve, which means that this will
-	// never return and the current swm binary will be replaced by tmux. This is
-	// precisely what we want as there is no sence in keeping swm running after
-	// attaching to a tmux session.
-	return syscall.Exec(tmuxPath, []string{"tmux", "-L" + t.socketName(), "attach", "-t" + sessionName}, os.Environ())
+
+	return found(matches[0])
 }
 
 // getSessionNameProjects returns a map of a project session name to the project
@@ -235,7 +495,7 @@ func (t *Manager) getSessionNameProjects() (map[string]ifaces.Project, error) {
 	// loop over all projects and get the session name
 	for _, prj := range t.code.Projects() {
 		// assign it to the map
-		sessionNameProjects[sanitizeSessionName(prj.String())] = prj
+		sessionNameProjects[t.multiplexer().SessionName(prj)] = prj
 	}
 
 	return sessionNameProjects, nil
@@ -248,105 +508,11 @@ func sanitizeSessionName(name string) string {
 	return name
 }
 
-func (t *Manager) VimExit() error {
-	// get the list of panes that are running vim
-	targets, err := t.getTargetsRunningVim()
-	if err != nil {
-		return err
-	}
-	log.Debug().Msgf("found the following tmux targets running vim: %v", targets)
-	// iterate over all the panes that has vim, and ask it to close itself
-	for _, target := range targets {
-		// Send the escape key, in the case we are in a vim like program. This is
-		// repeated because the send-key command is not waiting for vim to complete
-		// its action.
-		// Credit: https://gist.github.com/debugish/2773454
-		for i := 0; i < 25; i++ {
-			if err := exec.Command(tmuxPath, "-L", t.socketName(), "send-keys", "-t", target, "C-[").Run(); err != nil {
-				return err
-			}
-		}
-		// ask Vim to exit
-		if err := exec.Command(tmuxPath, "-L", t.socketName(), "send-keys", "-t", target, ":", "x", "a", "C-m").Run(); err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
-
-func (t *Manager) getTargetsRunningVim() ([]string, error) {
-	var targets []string
-
-	// get the list of sessions
-	var sessionNames []string
-	{
-		cmd := exec.Command(tmuxPath, "-L", t.socketName(), "list-sessions", "-F", "#{session_name}")
-		out, err := cmd.Output()
-		if err != nil {
-			return nil, err
-		}
-		for _, line := range strings.Split(string(out), "\n") {
-			if line != "" {
-				sessionNames = append(sessionNames, line)
-			}
-		}
-	}
-	log.Debug().Msgf("found the following tmux sessions: %v", sessionNames)
-	// iterate over the list of sessions, and for each session iterate over the
-	// list of windows, then over the panes and check what they are running
-	for _, sessionName := range sessionNames {
-		// get the list of windows for this session
-		var windowIDs []string
-		{
-			cmd := exec.Command(tmuxPath, "-L", t.socketName(), "list-windows", "-t", sessionName, "-F", "#I")
-			out, err := cmd.Output()
-			if err != nil {
-				return nil, err
-			}
-			for _, line := range strings.Split(string(out), "\n") {
-				if line != "" {
-					windowIDs = append(windowIDs, line)
-				}
-			}
-		}
-		// iterate over the list of windows, get the list of panes, and for each pane
-		// find out if it is running vim, nvim then will build targets
-		for _, windowID := range windowIDs {
-			// build the map of pane to tty
-			paneTTY := make(map[string]string)
-			{
-				cmd := exec.Command(tmuxPath, "-L", t.socketName(), "list-panes", "-t", sessionName+":"+windowID, "-F", "#P@#{pane_tty}")
-				out, err := cmd.Output()
-				if err != nil {
-					return nil, err
-				}
-				paneTTYs := strings.Split(string(out), "\n")
-				for _, paneTTYStr := range paneTTYs {
-					paneTTYArr := strings.Split(paneTTYStr, "@")
-					if len(paneTTYArr) < 2 {
-						continue
-					}
-					paneTTY[paneTTYArr[0]] = paneTTYArr[1]
-				}
-			}
-			// now iterate over the pane/tty, check what is running on that TTY
-			for paneID, ttyPath := range paneTTY {
-				// TODO: replace this sub-exec with real /proc parsing library for processes
-				// see https://github.com/mitchellh/go-ps/blob/master/process_linux.go
-				cmd := exec.Command(psPath, "-o", "state=", "-o", "comm=", "-t", ttyPath)
-				out, err := cmd.Output()
-				if err != nil {
-					return nil, err
-				}
-				// test the output against the psGrepRegexp
-				if psGrepRegexp.Match(out) {
-					targets = append(targets, fmt.Sprintf("%s:%s.%s", sessionName, windowID, paneID))
-				}
-			}
-		}
+// unsanitizeSessionName returns the import path of the project of the
+// session.
+func unsanitizeSessionName(name string) string {
+	name = strings.Replace(name, dotChar, ".", -1)
+	name = strings.Replace(name, colonChar, ":", -1)
 
-	}
-
-	return targets, nil
+	return name
 }
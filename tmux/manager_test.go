@@ -7,9 +7,13 @@ import (
 	"sort"
 	"testing"
 
+	"github.com/adrg/xdg"
 	"github.com/kalbasit/swm/code"
+	"github.com/kalbasit/swm/multiplexer"
+	"github.com/kalbasit/swm/selector"
 	"github.com/kalbasit/swm/story"
 	"github.com/kalbasit/swm/testhelper"
+	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/stretchr/testify/assert"
@@ -55,6 +59,10 @@ func TestGetSessionProjectsNoStory(t *testing.T) {
 	// delete it once we are done here
 	defer func() { os.RemoveAll(dir) }()
 
+	// keep the index of the code within the temporary directory
+	xdg.CacheHome = dir
+	defer xdg.Reload()
+
 	// create the filesystem we want to scan
 	require.NoError(t, testhelper.CreateProjects(dir))
 
@@ -103,6 +111,10 @@ func TestGetSessionProjectsStory123(t *testing.T) {
 	// delete it once we are done here
 	defer func() { os.RemoveAll(dir) }()
 
+	// keep the index of the code within the temporary directory
+	xdg.CacheHome = dir
+	defer xdg.Reload()
+
 	// create the filesystem we want to scan
 	require.NoError(t, testhelper.CreateProjects(dir))
 
@@ -149,6 +161,229 @@ func TestGetSessionProjectsStory123(t *testing.T) {
 	}
 }
 
+func TestGetSessionProjectsExclude(t *testing.T) {
+	// create a temporary directory
+	dir, err := ioutil.TempDir("", "swm-test-*")
+	require.NoError(t, err)
+
+	// delete it once we are done here
+	defer func() { os.RemoveAll(dir) }()
+
+	// keep the index of the code within the temporary directory
+	xdg.CacheHome = dir
+	defer xdg.Reload()
+
+	// create the filesystem we want to scan
+	require.NoError(t, testhelper.CreateProjects(dir))
+
+	// create a code excluding the projects of owner2
+	c := code.New(dir, regexp.MustCompile(`^github\.com/owner2(/|$)`))
+	require.NoError(t, c.Scan())
+
+	// create the tmux client
+	tmx := &Manager{code: c}
+
+	// get the session map
+	sessionNameProjects, err := tmx.getSessionNameProjects()
+	require.NoError(t, err)
+
+	var keys []string
+	for k := range sessionNameProjects {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	assert.Equal(t, []string{
+		"github" + dotChar + "com/owner1/repo1",
+		"github" + dotChar + "com/owner3/repo3",
+	}, keys)
+}
+
+func TestSelectProject(t *testing.T) {
+	// create a temporary directory
+	dir, err := ioutil.TempDir("", "swm-test-*")
+	require.NoError(t, err)
+
+	// delete it once we are done here
+	defer func() { os.RemoveAll(dir) }()
+
+	// keep the index of the code within the temporary directory
+	xdg.CacheHome = dir
+	xdg.DataHome = dir
+	defer xdg.Reload()
+
+	// create the filesystem we want to scan
+	require.NoError(t, testhelper.CreateProjects(dir))
+
+	// create a code
+	c := code.New(dir, regexp.MustCompile("^.snapshots$"))
+	require.NoError(t, c.Scan())
+
+	t.Run("the selected project is returned", func(t *testing.T) {
+		sel := &selector.Fake{Selection: "github" + dotChar + "com/owner2/repo2"}
+		tmx, err := New(c, "", WithSelector(sel))
+		require.NoError(t, err)
+
+		sessionName, prj, err := tmx.selectProject()
+		require.NoError(t, err)
+		assert.Equal(t, "github"+dotChar+"com/owner2/repo2", sessionName)
+		assert.Equal(t, "github.com/owner2/repo2", prj.String())
+		assert.Equal(t, []string{
+			"github" + dotChar + "com/owner1/repo1",
+			"github" + dotChar + "com/owner2/repo2",
+			"github" + dotChar + "com/owner3/repo3",
+		}, sel.Items)
+	})
+
+	t.Run("an unknown selection", func(t *testing.T) {
+		tmx, err := New(c, "", WithSelector(&selector.Fake{Selection: "unknown"}))
+		require.NoError(t, err)
+
+		_, _, err = tmx.selectProject()
+		assert.Equal(t, ErrProjectNotFoundForGivenSessionName, err)
+	})
+
+	t.Run("an aborted selection", func(t *testing.T) {
+		tmx, err := New(c, "", WithSelector(&selector.Fake{Err: selector.ErrNoSelection}))
+		require.NoError(t, err)
+
+		_, _, err = tmx.selectProject()
+		assert.Equal(t, selector.ErrNoSelection, err)
+	})
+}
+
+func TestResolveProject(t *testing.T) {
+	// create a temporary directory
+	dir, err := ioutil.TempDir("", "swm-test-*")
+	require.NoError(t, err)
+
+	// delete it once we are done here
+	defer func() { os.RemoveAll(dir) }()
+
+	// keep the index of the code within the temporary directory
+	xdg.CacheHome = dir
+	defer xdg.Reload()
+
+	// create the filesystem we want to scan
+	require.NoError(t, testhelper.CreateProjects(dir))
+
+	// create a code
+	c := code.New(dir, regexp.MustCompile("^.snapshots$"))
+	require.NoError(t, c.Scan())
+
+	tmx := &Manager{code: c}
+
+	for query, importPath := range map[string]string{
+		"github.com/owner2/repo2": "github.com/owner2/repo2",
+		"owner2/repo2":            "github.com/owner2/repo2",
+		"repo3":                   "github.com/owner3/repo3",
+		"/owner1/repo1/":          "github.com/owner1/repo1",
+		"o2r2":                    "github.com/owner2/repo2",
+	} {
+		t.Run(query, func(t *testing.T) {
+			sessionName, prj, err := tmx.resolveProject(query)
+			require.NoError(t, err)
+			assert.Equal(t, importPath, prj.String())
+			assert.Equal(t, sanitizeSessionName(importPath), sessionName)
+		})
+	}
+
+	t.Run("no match", func(t *testing.T) {
+		_, _, err := tmx.resolveProject("zzz")
+		assert.True(t, errors.Is(err, ErrNoProjectMatch))
+	})
+
+	t.Run("ambiguous", func(t *testing.T) {
+		_, _, err := tmx.resolveProject("repo")
+		require.True(t, errors.Is(err, ErrAmbiguousProject))
+		assert.Contains(t, err.Error(), "github.com/owner1/repo1, github.com/owner2/repo2, github.com/owner3/repo3")
+	})
+}
+
+func TestRankSessionNames(t *testing.T) {
+	// create a temporary directory
+	dir, err := ioutil.TempDir("", "swm-test-*")
+	require.NoError(t, err)
+
+	// delete it once we are done here
+	defer func() { os.RemoveAll(dir) }()
+
+	// keep the index and the history within the temporary directory
+	xdg.CacheHome = dir
+	xdg.DataHome = dir
+	defer xdg.Reload()
+
+	// create the filesystem we want to scan
+	require.NoError(t, testhelper.CreateProjects(dir))
+
+	// create a code
+	c := code.New(dir, regexp.MustCompile("^.snapshots$"))
+	require.NoError(t, c.Scan())
+
+	tmx := &Manager{code: c}
+	sessionNameProjects, err := tmx.getSessionNameProjects()
+	require.NoError(t, err)
+
+	// visit repo3 twice and repo2 once
+	for _, prj := range []string{"github.com/owner3/repo3", "github.com/owner2/repo2", "github.com/owner3/repo3"} {
+		tmx.recordVisit(sessionNameProjects[sanitizeSessionName(prj)])
+	}
+
+	t.Run("ordered by frecency", func(t *testing.T) {
+		assert.Equal(t, []string{
+			"github" + dotChar + "com/owner3/repo3",
+			"github" + dotChar + "com/owner2/repo2",
+			"github" + dotChar + "com/owner1/repo1",
+		}, tmx.rankSessionNames(sessionNameProjects, nil, tmx.histories()))
+	})
+
+	t.Run("running sessions first", func(t *testing.T) {
+		running := map[string]bool{"github" + dotChar + "com/owner1/repo1": true}
+		assert.Equal(t, []string{
+			"github" + dotChar + "com/owner1/repo1",
+			"github" + dotChar + "com/owner3/repo3",
+			"github" + dotChar + "com/owner2/repo2",
+		}, tmx.rankSessionNames(sessionNameProjects, running, tmx.histories()))
+	})
+
+	t.Run("recent projects are scored by the history of the story", func(t *testing.T) {
+		st, err := story.New("STORY-123", "")
+		require.NoError(t, err)
+		tmx := &Manager{code: c, story: st, client: &FakeClient{}}
+		tmx.recordVisit(sessionNameProjects[sanitizeSessionName("github.com/owner1/repo1")])
+
+		recent, err := tmx.Recent()
+		require.NoError(t, err)
+		if assert.Len(t, recent, 1) {
+			assert.Equal(t, "github.com/owner1/repo1", recent[0].Project.String())
+			assert.True(t, recent[0].Score > 0)
+		}
+
+		// the projects of the global history are not recent in another story
+		other, err := story.New("STORY-456", "")
+		require.NoError(t, err)
+		tmx = &Manager{code: c, story: other, client: &FakeClient{}}
+		recent, err = tmx.Recent()
+		require.NoError(t, err)
+		assert.Empty(t, recent)
+	})
+
+	t.Run("the running mark is stripped from the selection", func(t *testing.T) {
+		sel := &selector.Fake{Selection: "github" + dotChar + "com/owner3/repo3"}
+		f := &FakeClient{Outputs: map[string]string{"list-sessions": "github" + dotChar + "com/owner2/repo2"}}
+		tmx := &Manager{code: c, selector: sel, client: f}
+
+		_, prj, err := tmx.selectProject()
+		require.NoError(t, err)
+		assert.Equal(t, "github.com/owner3/repo3", prj.String())
+		assert.Equal(t, runningMark+"github"+dotChar+"com/owner2/repo2", sel.Items[0])
+
+		sel.Selection = runningMark + "github" + dotChar + "com/owner2/repo2"
+		_, prj, err = tmx.selectProject()
+		require.NoError(t, err)
+		assert.Equal(t, "github.com/owner2/repo2", prj.String())
+	})
+}
+
 func TestSanitizeSessionName(t *testing.T) {
 	t.Run(".", func(t *testing.T) {
 		assert.Equal(t, "github"+dotChar+"com/owner1/repo1", sanitizeSessionName("github.com/owner1/repo1"))
@@ -158,3 +393,52 @@ func TestSanitizeSessionName(t *testing.T) {
 		assert.Equal(t, "github"+colonChar+"com/owner1/repo1", sanitizeSessionName("github:com/owner1/repo1"))
 	})
 }
+
+func TestAttachCommand(t *testing.T) {
+	assert.Equal(t, "exec '/usr/bin/tmux' '-L' 'swm-STORY-123' attach-session -t '=github"+dotChar+"com/owner1/repo1'", attachCommand("/usr/bin/tmux", []string{"-L", "swm-STORY-123"}, "github"+dotChar+"com/owner1/repo1"))
+	assert.Equal(t, `exec '/usr/bin/tmux' '-L' 'swm-it'\''s' attach-session -t '=project'`, attachCommand("/usr/bin/tmux", []string{"-L", "swm-it's"}, "project"))
+	assert.Equal(t, `exec '/usr/bin/tmux' '-S' '/run/swm/swm-S1' '-f' '/code/.tmux.conf' attach-session -t '=project'`, attachCommand("/usr/bin/tmux", []string{"-S", "/run/swm/swm-S1", "-f", "/code/.tmux.conf"}, "project"))
+}
+
+func TestSwitchClientMultiplexer(t *testing.T) {
+	// create a temporary directory
+	dir, err := ioutil.TempDir("", "swm-test-*")
+	require.NoError(t, err)
+
+	// delete it once we are done here
+	defer func() { os.RemoveAll(dir) }()
+
+	// keep the index and the history within the temporary directory
+	xdg.CacheHome = dir
+	xdg.DataHome = dir
+	defer xdg.Reload()
+
+	// create the filesystem we want to scan
+	require.NoError(t, testhelper.CreateProjects(dir))
+
+	// create a code
+	c := code.New(dir, regexp.MustCompile("^.snapshots$"))
+	require.NoError(t, c.Scan())
+
+	mux := &multiplexer.Fake{Running: []string{"github.com_owner2_repo2"}}
+	sel := &selector.Fake{Selection: "github.com_owner1_repo1"}
+	f := &FakeClient{}
+	tmx := &Manager{code: c, selector: sel, client: f, mux: mux}
+
+	require.NoError(t, tmx.SwitchClient("owner3/repo3", false))
+	assert.Equal(t, "github.com_owner3_repo3", mux.Attached)
+
+	require.NoError(t, tmx.SwitchClient("", false))
+	assert.Equal(t, "github.com_owner1_repo1", mux.Attached)
+	// the running sessions are the ones of the multiplexer
+	assert.Equal(t, []string{runningMark + "github.com_owner3_repo3", runningMark + "github.com_owner2_repo2", "github.com_owner1_repo1"}, sel.Items)
+
+	require.NoError(t, tmx.KillServer(true))
+	if assert.NotNil(t, mux.Killed) {
+		assert.True(t, *mux.Killed)
+	}
+	require.NoError(t, tmx.Close())
+	assert.True(t, mux.Closed)
+	// nothing was run on tmux
+	assert.Empty(t, f.Commands)
+}
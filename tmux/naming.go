@@ -0,0 +1,251 @@
+package tmux
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// The templates of the names of the sessions.
+const (
+	// NamingImportPath names the session of a project after its import path.
+	NamingImportPath = "import-path"
+
+	// NamingOwnerRepo names the session of a project after the last two
+	// elements of its import path, its owner and its repository.
+	NamingOwnerRepo = "owner-repo"
+
+	// NamingRepo names the session of a project after its repository.
+	NamingRepo = "repo"
+)
+
+// ErrUnknownSessionNaming is returned by New if the session naming is not one
+// of SessionNamings.
+var ErrUnknownSessionNaming = errors.New("unknown session naming")
+
+// SessionNamings returns the templates of the names of the sessions.
+func SessionNamings() []string {
+	return []string{NamingImportPath, NamingOwnerRepo, NamingRepo}
+}
+
+// WithSessionNaming sets the template of the names of the sessions, it's
+// NamingImportPath by default. The names colliding with each other are
+// lengthened, see sessionNames.
+func WithSessionNaming(naming string) Option {
+	return func(m *Manager) { m.naming = naming }
+}
+
+// WithSessionAliases sets the names of the sessions of some projects, by
+// import path, over the template of the names.
+func WithSessionAliases(aliases map[string]string) Option {
+	return func(m *Manager) { m.aliases = aliases }
+}
+
+// validateNaming returns an error wrapping ErrUnknownSessionNaming if the
+// naming is unknown, an empty naming is NamingImportPath.
+func validateNaming(naming string) error {
+	switch naming {
+	case "", NamingImportPath, NamingOwnerRepo, NamingRepo:
+		return nil
+	default:
+		return errors.Wrapf(ErrUnknownSessionNaming, "%q is not one of %s", naming, strings.Join(SessionNamings(), ", "))
+	}
+}
+
+// namingDepth returns the number of elements of the import paths kept by
+// the naming, zero for all of them.
+func namingDepth(naming string) int {
+	switch naming {
+	case NamingOwnerRepo:
+		return 2
+	case NamingRepo:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// lastElements returns the last n elements of the import path, all of them
+// if n is zero.
+func lastElements(importPath string, n int) string {
+	elements := strings.Split(importPath, "/")
+	if n <= 0 || n >= len(elements) {
+		return importPath
+	}
+
+	return strings.Join(elements[len(elements)-n:], "/")
+}
+
+// sessionNames returns the names of the sessions of the projects by import
+// path. The names are given by the aliases or the naming, and are resolved
+// deterministically when they collide: the names that are not aliases are
+// lengthened one element of their import path at a time, and the names
+// still colliding are numbered (name~2, name~3) in the order of the import
+// paths, the aliases first.
+func sessionNames(importPaths []string, naming string, aliases map[string]string) map[string]string {
+	sorted := append([]string(nil), importPaths...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		_, iAlias := aliases[sorted[i]]
+		_, jAlias := aliases[sorted[j]]
+		if iAlias != jAlias {
+			return iAlias
+		}
+		return sorted[i] < sorted[j]
+	})
+
+	depths := make(map[string]int, len(sorted))
+	for _, ip := range sorted {
+		depths[ip] = namingDepth(naming)
+	}
+	name := func(ip string) string {
+		if alias, ok := aliases[ip]; ok {
+			return sanitizeSessionName(alias)
+		}
+		return sanitizeSessionName(lastElements(ip, depths[ip]))
+	}
+
+	// lengthen the colliding names until they no longer collide or they are
+	// the whole import paths
+	for grown := true; grown; {
+		grown = false
+		byName := make(map[string][]string)
+		for _, ip := range sorted {
+			byName[name(ip)] = append(byName[name(ip)], ip)
+		}
+		for _, ips := range byName {
+			if len(ips) < 2 {
+				continue
+			}
+			for _, ip := range ips {
+				if _, ok := aliases[ip]; ok || depths[ip] <= 0 {
+					continue
+				}
+				if depths[ip]++; depths[ip] >= len(strings.Split(ip, "/")) {
+					depths[ip] = 0
+				}
+				grown = true
+			}
+		}
+	}
+
+	// number the names that still collide
+	names := make(map[string]string, len(sorted))
+	used := make(map[string]bool, len(sorted))
+	var collided []string
+	for _, ip := range sorted {
+		if n := name(ip); !used[n] {
+			used[n] = true
+			names[ip] = n
+		} else {
+			collided = append(collided, ip)
+		}
+	}
+	for _, ip := range collided {
+		for i := 2; ; i++ {
+			if n := fmt.Sprintf("%s~%d", name(ip), i); !used[n] {
+				used[n] = true
+				names[ip] = n
+				break
+			}
+		}
+	}
+
+	return names
+}
+
+// sessionNameOf returns the name of the session of the project of the import
+// path, see sessionNames.
+func (t *Manager) sessionNameOf(importPath string) string {
+	if t.sessionNames == nil {
+		var importPaths []string
+		if t.code != nil {
+			for _, prj := range t.code.Projects() {
+				importPaths = append(importPaths, prj.String())
+			}
+		}
+		t.sessionNames = sessionNames(importPaths, t.naming, t.aliases)
+	}
+	if name, ok := t.sessionNames[importPath]; ok {
+		return name
+	}
+
+	return sessionNames([]string{importPath}, t.naming, t.aliases)[importPath]
+}
+
+// runningProjects returns the import paths of the projects of the running
+// sessions, or the windows of the session of the story in WindowPerProject,
+// by name. The project is given by the project option of the session, the
+// sessions created before the option was set are named after their project.
+func (t *Manager) runningProjects() map[string]string {
+	var (
+		out string
+		err error
+	)
+	if t.windowPerProject() {
+		out, err = t.client.Run("list-windows", "-t", "="+t.storySessionName(), "-F", "#{window_name}\t#{"+projectOption+"}")
+	} else {
+		out, err = t.client.Run("list-sessions", "-F", "#{session_name}\t#{"+projectOption+"}")
+	}
+	if err != nil {
+		// the server or the session of the story is not running
+		return nil
+	}
+
+	projects := make(map[string]string)
+	for _, line := range strings.Split(out, "\n") {
+		if line == "" {
+			continue
+		}
+		fields := strings.SplitN(line, "\t", 2)
+		var option string
+		if len(fields) == 2 {
+			option = fields[1]
+		}
+		projects[fields[0]] = optionProject(option, fields[0])
+	}
+
+	return projects
+}
+
+// claimName returns the name of the running session of the project, if any,
+// and true. Otherwise it returns the name, or a numbered variant of it if a
+// session of another project already has it, and false. The running
+// sessions are given by runningProjects.
+func claimName(name, importPath string, running map[string]string) (string, bool) {
+	if running[name] == importPath {
+		return name, true
+	}
+	names := make([]string, 0, len(running))
+	for n := range running {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		if running[n] == importPath {
+			return n, true
+		}
+	}
+
+	if _, ok := running[name]; !ok {
+		return name, false
+	}
+	for i := 2; ; i++ {
+		n := fmt.Sprintf("%s~%d", name, i)
+		if _, ok := running[n]; !ok {
+			return n, false
+		}
+	}
+}
+
+// optionProject returns the import path of the project of a session, a
+// window or a pane, given the value of its project option and the name of
+// its session.
+func optionProject(option, sessionName string) string {
+	if option != "" {
+		return option
+	}
+
+	return unsanitizeSessionName(sessionName)
+}
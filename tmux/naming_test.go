@@ -0,0 +1,160 @@
+package tmux
+
+import (
+	"io/ioutil"
+	"os"
+	"regexp"
+	"testing"
+
+	"github.com/adrg/xdg"
+	"github.com/kalbasit/swm/code"
+	"github.com/kalbasit/swm/testhelper"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionNames(t *testing.T) {
+	importPaths := []string{
+		"github.com/owner1/api",
+		"gitlab.com/owner1/api",
+		"github.com/owner2/api",
+		"github.com/owner1/web",
+		"github.com/kalbasit/swm",
+	}
+
+	t.Run("import-path", func(t *testing.T) {
+		names := sessionNames(importPaths, NamingImportPath, nil)
+		assert.Equal(t, "github"+dotChar+"com/owner1/api", names["github.com/owner1/api"])
+		assert.Equal(t, "github"+dotChar+"com/kalbasit/swm", names["github.com/kalbasit/swm"])
+	})
+
+	t.Run("owner-repo", func(t *testing.T) {
+		assert.Equal(t, map[string]string{
+			"github.com/owner1/api":   "github" + dotChar + "com/owner1/api",
+			"gitlab.com/owner1/api":   "gitlab" + dotChar + "com/owner1/api",
+			"github.com/owner2/api":   "owner2/api",
+			"github.com/owner1/web":   "owner1/web",
+			"github.com/kalbasit/swm": "kalbasit/swm",
+		}, sessionNames(importPaths, NamingOwnerRepo, nil))
+	})
+
+	t.Run("repo", func(t *testing.T) {
+		assert.Equal(t, map[string]string{
+			"github.com/owner1/api":   "github" + dotChar + "com/owner1/api",
+			"gitlab.com/owner1/api":   "gitlab" + dotChar + "com/owner1/api",
+			"github.com/owner2/api":   "owner2/api",
+			"github.com/owner1/web":   "web",
+			"github.com/kalbasit/swm": "swm",
+		}, sessionNames(importPaths, NamingRepo, nil))
+	})
+
+	t.Run("aliases", func(t *testing.T) {
+		aliases := map[string]string{
+			"github.com/owner1/api": "web",
+			"gitlab.com/owner1/api": "web",
+			"github.com/owner2/api": "api",
+		}
+		assert.Equal(t, map[string]string{
+			"github.com/owner1/api":   "web",
+			"gitlab.com/owner1/api":   "web~2",
+			"github.com/owner2/api":   "api",
+			"github.com/owner1/web":   "owner1/web",
+			"github.com/kalbasit/swm": "swm",
+		}, sessionNames(importPaths, NamingRepo, aliases))
+	})
+
+	t.Run("deterministic", func(t *testing.T) {
+		reversed := make([]string, len(importPaths))
+		for i, ip := range importPaths {
+			reversed[len(importPaths)-1-i] = ip
+		}
+		assert.Equal(t, sessionNames(importPaths, NamingRepo, nil), sessionNames(reversed, NamingRepo, nil))
+	})
+}
+
+func TestValidateNaming(t *testing.T) {
+	for _, naming := range append(SessionNamings(), "") {
+		assert.NoError(t, validateNaming(naming))
+	}
+	assert.True(t, errors.Is(validateNaming("owner"), ErrUnknownSessionNaming))
+}
+
+func TestClaimName(t *testing.T) {
+	running := map[string]string{
+		"api":   "github.com/owner1/api",
+		"api~2": "github.com/owner2/api",
+		"old":   "github.com/owner1/web",
+	}
+
+	for importPath, want := range map[string]struct {
+		name    string
+		running bool
+	}{
+		"github.com/owner1/api": {"api", true},
+		"github.com/owner2/api": {"api~2", true},
+		"github.com/owner1/web": {"old", true},
+		"gitlab.com/owner1/api": {"api~3", false},
+	} {
+		name, ok := claimName("api", importPath, running)
+		assert.Equal(t, want.name, name, importPath)
+		assert.Equal(t, want.running, ok, importPath)
+	}
+
+	name, ok := claimName("web", "github.com/owner3/web", running)
+	assert.Equal(t, "web", name)
+	assert.False(t, ok)
+}
+
+func TestEnsureSessionNaming(t *testing.T) {
+	// create a temporary directory
+	dir, err := ioutil.TempDir("", "swm-test-*")
+	require.NoError(t, err)
+
+	// delete it once we are done here
+	defer func() { os.RemoveAll(dir) }()
+
+	// keep the index and the vim sessions within the temporary directory
+	xdg.CacheHome = dir
+	xdg.DataHome = dir
+	defer xdg.Reload()
+
+	// create the filesystem we want to scan
+	require.NoError(t, testhelper.CreateProjects(dir))
+
+	// create a code
+	c := code.New(dir, regexp.MustCompile("^.snapshots$"))
+	require.NoError(t, c.Scan())
+	prj, err := c.GetProjectByRelativePath("github.com/owner1/repo1")
+	require.NoError(t, err)
+
+	t.Run("the session is created with its project", func(t *testing.T) {
+		f := &FakeClient{}
+		tmx := &Manager{code: c, client: f, naming: NamingRepo}
+
+		sessionName, err := tmx.EnsureSession(prj)
+		require.NoError(t, err)
+		assert.Equal(t, "repo1", sessionName)
+		assert.Contains(t, f.Commands, "set-option -t =repo1: "+projectOption+" github.com/owner1/repo1")
+	})
+
+	t.Run("the running session is found by its project", func(t *testing.T) {
+		f := &FakeClient{Outputs: map[string]string{"list-sessions": "api\tgithub.com/owner1/repo1"}}
+		tmx := &Manager{code: c, client: f, naming: NamingRepo}
+
+		sessionName, err := tmx.EnsureSession(prj)
+		require.NoError(t, err)
+		assert.Equal(t, "api", sessionName)
+		assert.Len(t, f.Commands, 1)
+		assert.Equal(t, map[string]bool{"repo1": true}, tmx.runningSessionNames())
+	})
+
+	t.Run("the name is used by another project", func(t *testing.T) {
+		f := &FakeClient{Outputs: map[string]string{"list-sessions": "repo1\tgitlab.com/owner1/repo1"}}
+		tmx := &Manager{code: c, client: f, naming: NamingRepo}
+
+		sessionName, err := tmx.EnsureSession(prj)
+		require.NoError(t, err)
+		assert.Equal(t, "repo1~2", sessionName)
+	})
+}
@@ -0,0 +1,311 @@
+package tmux
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/kalbasit/swm/tools"
+	"github.com/pkg/errors"
+)
+
+// procRoot is where the proc filesystem is mounted.
+const procRoot = "/proc"
+
+// Process is a process attached to a terminal.
+type Process struct {
+	PID  int
+	PPID int
+	PGID int
+
+	// TPGID is the foreground process group of the terminal of the process.
+	TPGID int
+
+	// TTY is the path of the terminal, such as /dev/pts/3.
+	TTY string
+
+	// State is the state of the process as reported by ps, such as R for
+	// running, S for sleeping, T for stopped or Z for zombie.
+	State string
+
+	// Comm is the name of the program.
+	Comm string
+
+	// Args are the arguments of the process, starting with the program.
+	Args []string
+
+	// Cwd is the working directory of the process, it's empty if it cannot
+	// be read.
+	Cwd string
+
+	// Children are the children of the process attached to the same terminal.
+	Children []*Process
+}
+
+// CommandLine returns the command line of the process, quoted like
+// CommandLine, or the name of the program if the arguments are not known.
+func (p *Process) CommandLine() string {
+	if len(p.Args) == 0 {
+		return p.Comm
+	}
+
+	return CommandLine(p.Args)
+}
+
+// ProcessTable holds the processes by the path of their terminal, they're
+// sorted by pid.
+type ProcessTable map[string][]*Process
+
+// NewProcessTable returns the table of the processes, the children of each
+// process are linked to it.
+func NewProcessTable(processes []Process) ProcessTable {
+	t := make(ProcessTable)
+	byPID := make(map[int]*Process, len(processes))
+	for i := range processes {
+		p := processes[i]
+		p.Children = nil
+		t[p.TTY] = append(t[p.TTY], &p)
+		byPID[p.PID] = &p
+	}
+
+	for _, ps := range t {
+		sort.Slice(ps, func(i, j int) bool { return ps[i].PID < ps[j].PID })
+		for _, p := range ps {
+			if parent, ok := byPID[p.PPID]; ok && parent.TTY == p.TTY {
+				parent.Children = append(parent.Children, p)
+			}
+		}
+	}
+
+	return t
+}
+
+// Foreground returns the leader of the foreground process group of the
+// terminal, it's nil if the shell of the pane is in the foreground.
+func (t ProcessTable) Foreground(tty string, shellPID int) *Process {
+	var group *Process
+	for _, p := range t[tty] {
+		if p.TPGID <= 0 || p.PGID != p.TPGID {
+			continue
+		}
+		if p.PID == p.PGID {
+			group = p
+			break
+		}
+		// the leader exited, the oldest process of the group stands for it
+		if group == nil {
+			group = p
+		}
+	}
+	if group == nil || group.PID == shellPID {
+		return nil
+	}
+
+	return group
+}
+
+// ProcessInspector lists the processes attached to a terminal.
+type ProcessInspector interface {
+	Processes() (ProcessTable, error)
+}
+
+// WithProcessInspector sets the inspector of the processes running in the
+// panes. It defaults to reading /proc, or to ps if there's no /proc.
+func WithProcessInspector(i ProcessInspector) Option {
+	return func(m *Manager) { m.processes = i }
+}
+
+// defaultProcessInspector returns the inspector reading /proc if it's
+// mounted, and running ps otherwise.
+func defaultProcessInspector() ProcessInspector {
+	if _, err := os.Stat(path.Join(procRoot, "self", "stat")); err == nil {
+		return &ProcInspector{Root: procRoot}
+	}
+
+	return &PsInspector{}
+}
+
+// ProcInspector lists the processes by walking the proc filesystem of Linux
+// in one pass.
+type ProcInspector struct {
+	// Root is where the proc filesystem is mounted.
+	Root string
+}
+
+// Processes returns the processes attached to a terminal.
+func (i *ProcInspector) Processes() (ProcessTable, error) {
+	entries, err := ioutil.ReadDir(i.Root)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error listing the processes in %s", i.Root)
+	}
+
+	var processes []Process
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+		p, err := i.process(pid)
+		if err != nil {
+			// the process exited while walking the processes
+			if os.IsNotExist(err) || errors.Is(err, syscall.ESRCH) {
+				continue
+			}
+			return nil, err
+		}
+		if p.TTY != "" {
+			processes = append(processes, *p)
+		}
+	}
+
+	return NewProcessTable(processes), nil
+}
+
+// process reads the process from its directory, the terminal is empty if
+// it's not attached to one.
+func (i *ProcInspector) process(pid int) (*Process, error) {
+	dir := path.Join(i.Root, strconv.Itoa(pid))
+
+	stat, err := ioutil.ReadFile(path.Join(dir, "stat"))
+	if err != nil {
+		return nil, err
+	}
+	p, err := parseProcStat(string(stat))
+	if err != nil {
+		return nil, errors.Wrapf(err, "error parsing %s", path.Join(dir, "stat"))
+	}
+	if p.TTY == "" {
+		return p, nil
+	}
+
+	cmdline, err := ioutil.ReadFile(path.Join(dir, "cmdline"))
+	if err != nil {
+		return nil, err
+	}
+	if s := strings.TrimRight(string(cmdline), "\x00"); s != "" {
+		p.Args = strings.Split(s, "\x00")
+	}
+	// the working directory of the processes of the other users cannot be
+	// read
+	p.Cwd, _ = os.Readlink(path.Join(dir, "cwd"))
+
+	return p, nil
+}
+
+// parseProcStat parses the content of /proc/<pid>/stat.
+func parseProcStat(stat string) (*Process, error) {
+	// the name of the program is within parentheses and may contain both
+	// spaces and parentheses
+	open, closing := strings.IndexByte(stat, '('), strings.LastIndexByte(stat, ')')
+	if open < 0 || closing < open {
+		return nil, errors.Errorf("unexpected stat %q", stat)
+	}
+	fields := strings.Fields(stat[closing+1:])
+	if len(fields) < 6 {
+		return nil, errors.Errorf("unexpected stat %q", stat)
+	}
+
+	p := &Process{Comm: stat[open+1 : closing], State: fields[0]}
+	ints := make([]int, 5)
+	for j := range ints {
+		n, err := strconv.Atoi(fields[j+1])
+		if err != nil {
+			return nil, errors.Errorf("unexpected stat %q", stat)
+		}
+		ints[j] = n
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(stat[:open]))
+	if err != nil {
+		return nil, errors.Errorf("unexpected stat %q", stat)
+	}
+	p.PID, p.PPID, p.PGID, p.TPGID = pid, ints[0], ints[1], ints[4]
+	p.TTY = ttyName(ints[3])
+
+	return p, nil
+}
+
+// ttyName returns the path of the terminal with the device number found in
+// /proc/<pid>/stat, it's empty if the number is zero.
+func ttyName(nr int) string {
+	if nr == 0 {
+		return ""
+	}
+	major := (nr >> 8) & 0xfff
+	minor := (nr & 0xff) | ((nr >> 12) & 0xfff00)
+
+	switch {
+	case major >= 136 && major <= 143:
+		// the pseudo terminals, as opened by tmux
+		return fmt.Sprintf("/dev/pts/%d", (major-136)<<8|minor)
+	case major == 4 && minor < 64:
+		return fmt.Sprintf("/dev/tty%d", minor)
+	case major == 4:
+		return fmt.Sprintf("/dev/ttyS%d", minor-64)
+	default:
+		return fmt.Sprintf("/dev/char/%d:%d", major, minor)
+	}
+}
+
+// PsInspector lists the processes with one call to ps, for the systems
+// without a proc filesystem. The working directory of the processes is not
+// known and their arguments are split on spaces.
+type PsInspector struct{}
+
+// Processes returns the processes attached to a terminal.
+func (*PsInspector) Processes() (ProcessTable, error) {
+	psPath, err := tools.Path(tools.Ps)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := exec.Command(psPath, "-A", "-o", "pid=", "-o", "ppid=", "-o", "pgid=", "-o", "tpgid=", "-o", "state=", "-o", "tty=", "-o", "args=").Output()
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing the processes")
+	}
+
+	return parsePs(string(out)), nil
+}
+
+// parsePs parses the output of ps -o pid=,ppid=,pgid=,tpgid=,state=,tty=,args=
+// and keeps the processes attached to a terminal.
+func parsePs(out string) ProcessTable {
+	var processes []Process
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 7 || fields[5] == "?" || fields[5] == "??" || fields[5] == "-" {
+			continue
+		}
+		ints := make([]int, 4)
+		var err error
+		for j := range ints {
+			if ints[j], err = strconv.Atoi(fields[j]); err != nil {
+				break
+			}
+		}
+		if err != nil {
+			continue
+		}
+		tty := fields[5]
+		if !strings.HasPrefix(tty, "/dev/") {
+			tty = "/dev/" + tty
+		}
+		processes = append(processes, Process{
+			PID:   ints[0],
+			PPID:  ints[1],
+			PGID:  ints[2],
+			TPGID: ints[3],
+			State: fields[4][:1],
+			TTY:   tty,
+			Comm:  path.Base(fields[6]),
+			Args:  fields[6:],
+		})
+	}
+
+	return NewProcessTable(processes)
+}
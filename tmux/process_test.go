@@ -0,0 +1,139 @@
+package tmux
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessTable(t *testing.T) {
+	table := NewProcessTable([]Process{
+		{PID: 201, PPID: 200, PGID: 200, TPGID: 200, TTY: "/dev/pts/1", Comm: "vim-helper", Args: []string{"vim-helper", "--stdio"}},
+		{PID: 200, PPID: 100, PGID: 200, TPGID: 200, TTY: "/dev/pts/1", Comm: "vim", Args: []string{"vim", "main.go", "release notes.md"}},
+		{PID: 100, PPID: 1, PGID: 100, TPGID: 200, TTY: "/dev/pts/1", Comm: "zsh", Args: []string{"-zsh"}},
+		{PID: 300, PPID: 1, PGID: 300, TPGID: 300, TTY: "/dev/pts/2", Comm: "zsh", Args: []string{"-zsh"}},
+		{PID: 301, PPID: 300, PGID: 301, TPGID: 300, TTY: "/dev/pts/2", Comm: "sleep", Args: []string{"sleep", "100"}},
+		{PID: 401, PPID: 1, PGID: 400, TPGID: 400, TTY: "/dev/pts/3", Comm: "make"},
+		{PID: 500, PPID: 1, PGID: 500, TPGID: 500, TTY: "/dev/pts/5", Comm: "zsh", Args: []string{"-zsh"}},
+	})
+
+	t.Run("the tree", func(t *testing.T) {
+		require.Len(t, table["/dev/pts/1"], 3)
+		shell := table["/dev/pts/1"][0]
+		assert.Equal(t, 100, shell.PID)
+		require.Len(t, shell.Children, 1)
+		assert.Equal(t, 200, shell.Children[0].PID)
+		require.Len(t, shell.Children[0].Children, 1)
+		assert.Equal(t, 201, shell.Children[0].Children[0].PID)
+	})
+
+	t.Run("a command", func(t *testing.T) {
+		fg := table.Foreground("/dev/pts/1", 100)
+		require.NotNil(t, fg)
+		assert.Equal(t, "vim main.go 'release notes.md'", fg.CommandLine())
+	})
+
+	t.Run("the shell", func(t *testing.T) {
+		assert.Nil(t, table.Foreground("/dev/pts/5", 500))
+	})
+
+	t.Run("a background command", func(t *testing.T) {
+		assert.Nil(t, table.Foreground("/dev/pts/2", 300))
+	})
+
+	t.Run("the leader exited", func(t *testing.T) {
+		fg := table.Foreground("/dev/pts/3", 300)
+		require.NotNil(t, fg)
+		assert.Equal(t, "make", fg.CommandLine())
+	})
+
+	t.Run("an unknown terminal", func(t *testing.T) {
+		assert.Nil(t, table.Foreground("/dev/pts/4", 600))
+	})
+}
+
+func TestParseProcStat(t *testing.T) {
+	p, err := parseProcStat("1234 (tmux: server) S 1 1234 1234 34817 5678 4194560 1207 0 0 0\n")
+	require.NoError(t, err)
+	assert.Equal(t, &Process{PID: 1234, PPID: 1, PGID: 1234, TPGID: 5678, TTY: "/dev/pts/1", State: "S", Comm: "tmux: server"}, p)
+
+	p, err = parseProcStat("42 (a) b)) R 2 0 0 0 -1 4194560")
+	require.NoError(t, err)
+	assert.Equal(t, "a) b)", p.Comm)
+	assert.Empty(t, p.TTY)
+
+	for _, stat := range []string{"", "42 (vim", "42 (vim) S 1", "x (vim) S 1 1 1 1 1"} {
+		_, err := parseProcStat(stat)
+		assert.Error(t, err, stat)
+	}
+}
+
+func TestTTYName(t *testing.T) {
+	for nr, want := range map[int]string{
+		0:        "",
+		34816:    "/dev/pts/0",
+		34817:    "/dev/pts/1",
+		35072:    "/dev/pts/256",
+		1025:     "/dev/tty1",
+		1088:     "/dev/ttyS0",
+		5<<8 | 1: "/dev/char/5:1",
+	} {
+		assert.Equal(t, want, ttyName(nr), nr)
+	}
+}
+
+func TestProcInspector(t *testing.T) {
+	// create a temporary directory
+	dir, err := ioutil.TempDir("", "swm-test-*")
+	require.NoError(t, err)
+
+	// delete it once we are done here
+	defer func() { os.RemoveAll(dir) }()
+
+	// build a fake /proc
+	for pid, files := range map[string]map[string]string{
+		"100": {"stat": "100 (zsh) S 1 100 100 34817 200 0", "cmdline": "-zsh\x00"},
+		"200": {"stat": "200 (vim) S 100 200 100 34817 200 0", "cmdline": "vim\x00main.go\x00"},
+		"300": {"stat": "300 (kworker/0:1) I 2 0 0 0 -1 0", "cmdline": ""},
+	} {
+		require.NoError(t, os.MkdirAll(path.Join(dir, pid), 0755))
+		for name, content := range files {
+			require.NoError(t, ioutil.WriteFile(path.Join(dir, pid, name), []byte(content), 0644))
+		}
+	}
+	require.NoError(t, os.Symlink("/code/repo1", path.Join(dir, "200", "cwd")))
+	// not a process
+	require.NoError(t, ioutil.WriteFile(path.Join(dir, "uptime"), []byte("1 1"), 0644))
+	// a process exiting while the processes are walked
+	require.NoError(t, os.MkdirAll(path.Join(dir, "400"), 0755))
+
+	table, err := (&ProcInspector{Root: dir}).Processes()
+	require.NoError(t, err)
+
+	require.Len(t, table, 1)
+	require.Len(t, table["/dev/pts/1"], 2)
+	vim := table["/dev/pts/1"][1]
+	assert.Equal(t, []string{"vim", "main.go"}, vim.Args)
+	assert.Equal(t, "/code/repo1", vim.Cwd)
+	assert.Empty(t, table["/dev/pts/1"][0].Cwd)
+	assert.Equal(t, vim, table.Foreground("/dev/pts/1", 100))
+}
+
+func TestParsePs(t *testing.T) {
+	out := `    1     0     1     -1 Ss   ?        /sbin/init
+  100     1   100    200 Ss   pts/1    -zsh
+  200   100   200    200 S+   pts/1    vim main.go
+  300     1   300    300 Ss+  ttys003  /bin/zsh -l
+`
+	table := parsePs(out)
+	require.Len(t, table, 2)
+	fg := table.Foreground("/dev/pts/1", 100)
+	require.NotNil(t, fg)
+	assert.Equal(t, &Process{PID: 200, PPID: 100, PGID: 200, TPGID: 200, TTY: "/dev/pts/1", State: "S", Comm: "vim", Args: []string{"vim", "main.go"}}, fg)
+	assert.Nil(t, table.Foreground("/dev/ttys003", 300))
+	assert.Len(t, table["/dev/pts/1"][0].Children, 1)
+}
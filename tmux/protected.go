@@ -0,0 +1,339 @@
+package tmux
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	// ErrProtectedProcessFound is returned by KillServer if a protected
+	// process is running on the server and it was not asked to exit them.
+	ErrProtectedProcessFound = errors.New("a protected process was found, cannot exit server to avoid data loss")
+
+	// ErrProtectedProcessRefusedToExit is returned if a protected process is
+	// still running after it was asked to exit, or if it has no exit keys.
+	ErrProtectedProcessRefusedToExit = errors.New("a protected process refused to exit")
+
+	// ErrInvalidProtectedProcess is returned by New if a protected process
+	// cannot be used.
+	ErrInvalidProtectedProcess = errors.New("invalid protected process")
+)
+
+// vimSessionPlaceholder is replaced, in the exit keys, by the path of the
+// vim session file of the project of the pane.
+const vimSessionPlaceholder = "{vim-session}"
+
+// vimProcessName is the name of the protected process exited by VimExit.
+const vimProcessName = "vim"
+
+// defaultExitTimeout is how long a protected process is given to exit if its
+// rule has no timeout.
+const defaultExitTimeout = 5 * time.Second
+
+// exitPollInterval is how often the panes are checked while waiting for the
+// protected processes to exit.
+const exitPollInterval = 100 * time.Millisecond
+
+// ProtectedProcess describes a process that must not be killed with the
+// server, such as an editor with unsaved changes or a running test suite.
+type ProtectedProcess struct {
+	// Name identifies the process in the reports.
+	Name string `mapstructure:"name"`
+
+	// Match is a regular expression matched against the command running in
+	// the foreground of the pane, the path of the program is stripped.
+	Match string `mapstructure:"match"`
+
+	// ExitKeys are sent, with tmux send-keys, to ask the process to exit.
+	// They're either key names (Escape, Enter, C-c) or text. The process
+	// cannot exit gracefully if it has no exit keys.
+	ExitKeys []string `mapstructure:"exit-keys"`
+
+	// Timeout is how long the process is given to exit.
+	Timeout time.Duration `mapstructure:"timeout"`
+
+	re *regexp.Regexp
+}
+
+func (p *ProtectedProcess) compile() error {
+	if p.Name == "" {
+		return errors.Wrap(ErrInvalidProtectedProcess, "the name is required")
+	}
+	re, err := regexp.Compile(p.Match)
+	if err != nil {
+		return errors.Wrapf(ErrInvalidProtectedProcess, "%s: error compiling the match pattern: %s", p.Name, err)
+	}
+	if p.Timeout < 0 {
+		return errors.Wrapf(ErrInvalidProtectedProcess, "%s: the timeout is negative", p.Name)
+	}
+	p.re = re
+
+	return nil
+}
+
+// matches returns true if the command, with the path of its program
+// stripped, is this process.
+func (p *ProtectedProcess) matches(command string) bool {
+	return command != "" && p.re.MatchString(command)
+}
+
+func (p *ProtectedProcess) timeout() time.Duration {
+	if p.Timeout == 0 {
+		return defaultExitTimeout
+	}
+
+	return p.Timeout
+}
+
+// DefaultProtectedProcesses returns the processes protected unless the
+// configuration says otherwise: the terminal editors exit if they have no
+// unsaved changes, the test suites are interrupted and the remote shells are
+// never killed.
+func DefaultProtectedProcesses() []ProtectedProcess {
+	// send the escape key many times as send-keys does not wait for vim to
+	// complete its action, credit: https://gist.github.com/debugish/2773454
+	var vimKeys []string
+	for i := 0; i < 25; i++ {
+		vimKeys = append(vimKeys, "C-[")
+	}
+	vimKeys = append(vimKeys, ":mksession! "+vimSessionPlaceholder, "Enter", ":xa", "Enter")
+
+	return []ProtectedProcess{
+		{Name: vimProcessName, Match: `^(g?n?vim?x?)( |$)`, ExitKeys: vimKeys},
+		{Name: "emacs", Match: `^emacs( |$)`, ExitKeys: []string{"C-g", "C-x", "C-c"}},
+		{Name: "helix", Match: `^(hx|helix)( |$)`, ExitKeys: []string{"Escape", ":quit-all", "Enter"}},
+		{Name: "kakoune", Match: `^kak( |$)`, ExitKeys: []string{"Escape", ":quit", "Enter"}},
+		{Name: "test suite", Match: `^((go|cargo|npm|yarn|make) test|pytest|jest|rspec)( |$)`, ExitKeys: []string{"C-c"}, Timeout: 10 * time.Second},
+		{Name: "remote shell", Match: `^(ssh|mosh|mosh-client)( |$)`},
+	}
+}
+
+// WithProtectedProcesses sets the protected processes, they replace the
+// default ones. No process is protected if none is given.
+func WithProtectedProcesses(processes []ProtectedProcess) Option {
+	return func(m *Manager) { m.protectedProcesses = append([]ProtectedProcess{}, processes...) }
+}
+
+// compileProtectedProcesses compiles the match patterns of the processes, it
+// returns an error wrapping ErrInvalidProtectedProcess if one cannot be used.
+func compileProtectedProcesses(processes []ProtectedProcess) error {
+	for i := range processes {
+		if err := processes[i].compile(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// busyPane is a pane running a protected process in the foreground.
+type busyPane struct {
+	target      string
+	sessionName string
+	project     string
+	pid         int
+	tty         string
+	command     string
+	process     *ProtectedProcess
+}
+
+func (p busyPane) String() string {
+	return fmt.Sprintf("%s (%s)", p.target, p.process.Name)
+}
+
+// busyPanesFormat are the fields of the panes listed by tmux, separated by
+// tabs.
+var busyPanesFormat = strings.Join([]string{
+	"#{session_name}",
+	"#{window_index}",
+	"#{pane_index}",
+	"#{pane_pid}",
+	"#{pane_tty}",
+	"#{" + projectOption + "}",
+}, "\t")
+
+// getBusyPanes returns the panes running one of the protected processes.
+func (t *Manager) getBusyPanes(processes []ProtectedProcess) ([]busyPane, error) {
+	out, err := t.client.Run("list-panes", "-a", "-F", busyPanesFormat)
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing the panes")
+	}
+
+	table, err := t.processes.Processes()
+	if err != nil {
+		return nil, err
+	}
+
+	var panes []busyPane
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 6 {
+			continue
+		}
+		pid, err := strconv.Atoi(fields[3])
+		if err != nil {
+			return nil, errors.Wrapf(err, "error parsing the pane pid of %q", line)
+		}
+		pane := busyPane{
+			target:      fmt.Sprintf("%s:%s.%s", fields[0], fields[1], fields[2]),
+			sessionName: fields[0],
+			project:     optionProject(fields[5], fields[0]),
+			pid:         pid,
+			tty:         fields[4],
+		}
+		fg := table.Foreground(pane.tty, pane.pid)
+		if fg == nil {
+			continue
+		}
+		if pane.process = protectedProcess(processes, fg.CommandLine()); pane.process != nil {
+			pane.command = fg.CommandLine()
+			panes = append(panes, pane)
+		}
+	}
+	log.Debug().Msgf("found the following tmux panes running a protected process: %v", panes)
+
+	return panes, nil
+}
+
+// protectedProcess returns the first of the protected processes matching
+// the command.
+func protectedProcess(processes []ProtectedProcess, command string) *ProtectedProcess {
+	command = programBase(command)
+	for i := range processes {
+		if p := &processes[i]; p.matches(command) {
+			return p
+		}
+	}
+
+	return nil
+}
+
+// programBase returns the command with the path of its program stripped.
+func programBase(command string) string {
+	if command == "" {
+		return ""
+	}
+	if i := strings.IndexByte(command, ' '); i > 0 {
+		return path.Base(command[:i]) + command[i:]
+	}
+
+	return path.Base(command)
+}
+
+// exitProcesses asks the protected processes of the panes to exit, then
+// waits for them to exit. It returns an error wrapping
+// ErrProtectedProcessRefusedToExit and listing the panes still running a
+// protected process. Nothing is sent if one of the processes has no exit
+// keys, as it would refuse to exit anyway.
+func (t *Manager) exitProcesses(panes []busyPane) error {
+	var refused []busyPane
+	for _, pane := range panes {
+		if len(pane.process.ExitKeys) == 0 {
+			refused = append(refused, pane)
+		}
+	}
+
+	if len(refused) == 0 {
+		sessions := make(map[string]bool)
+		for _, pane := range panes {
+			keys, err := t.exitKeys(pane, sessions)
+			if err != nil {
+				return err
+			}
+			if err := t.SendKeys(pane.target, keys...); err != nil {
+				return err
+			}
+		}
+		refused = waitExit(panes, t.isStillRunning)
+	}
+
+	if len(refused) > 0 {
+		reports := make([]string, 0, len(refused))
+		for _, pane := range refused {
+			reports = append(reports, pane.String())
+		}
+		return errors.Wrapf(ErrProtectedProcessRefusedToExit, "still running in %s", strings.Join(reports, ", "))
+	}
+
+	return nil
+}
+
+// exitKeys returns the exit keys of the process of the pane, with the path
+// of the vim session file of its project. The session files of the other
+// panes of the same project are saved alongside.
+func (t *Manager) exitKeys(pane busyPane, sessions map[string]bool) ([]string, error) {
+	keys := pane.process.ExitKeys
+
+	var hasSession bool
+	for _, key := range keys {
+		hasSession = hasSession || strings.Contains(key, vimSessionPlaceholder)
+	}
+	if !hasSession {
+		return keys, nil
+	}
+
+	name := pane.project
+	if sessions[name] {
+		name = fmt.Sprintf("%s.%s", name, strings.TrimPrefix(pane.target, pane.sessionName+":"))
+	}
+	sessions[name] = true
+	sessionPath := t.vimSessionPath(name)
+	if err := os.MkdirAll(path.Dir(sessionPath), 0755); err != nil {
+		return nil, errors.Wrap(err, "error creating the directory of the vim sessions")
+	}
+
+	res := make([]string, 0, len(keys))
+	for _, key := range keys {
+		res = append(res, strings.Replace(key, vimSessionPlaceholder, vimEscape(sessionPath), -1))
+	}
+
+	return res, nil
+}
+
+// isStillRunning returns true if the protected process of the pane is still
+// running in the foreground.
+func (t *Manager) isStillRunning(pane busyPane) (bool, error) {
+	table, err := t.processes.Processes()
+	if err != nil {
+		return false, err
+	}
+	fg := table.Foreground(pane.tty, pane.pid)
+
+	return fg != nil && pane.process.matches(programBase(fg.CommandLine())), nil
+}
+
+// waitExit polls the panes until their protected processes exit or their
+// timeouts expire, it returns the panes still running a protected process.
+func waitExit(panes []busyPane, running func(busyPane) (bool, error)) []busyPane {
+	start := time.Now()
+	var refused []busyPane
+	for len(panes) > 0 {
+		var remaining []busyPane
+		for _, pane := range panes {
+			ok, err := running(pane)
+			if err != nil {
+				log.Warn().Err(err).Str("target", pane.target).Msg("error checking if the protected process exited")
+			}
+			switch {
+			case !ok && err == nil:
+			case time.Since(start) >= pane.process.timeout():
+				refused = append(refused, pane)
+			default:
+				remaining = append(remaining, pane)
+			}
+		}
+		if panes = remaining; len(panes) > 0 {
+			time.Sleep(exitPollInterval)
+		}
+	}
+
+	return refused
+}
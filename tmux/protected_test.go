@@ -0,0 +1,207 @@
+package tmux
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/adrg/xdg"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProtectedProcess(t *testing.T) {
+	processes := DefaultProtectedProcesses()
+	require.NoError(t, compileProtectedProcesses(processes))
+
+	for command, want := range map[string]string{
+		"vim":                      "vim",
+		"/usr/bin/nvim main.go":    "vim",
+		"vi -R README.md":          "vim",
+		"emacs -nw":                "emacs",
+		"hx .":                     "helix",
+		"kak":                      "kakoune",
+		"go test ./...":            "test suite",
+		"/usr/local/bin/pytest -x": "test suite",
+		"ssh example.com":          "remote shell",
+		"mosh-client":              "remote shell",
+		"":                         "",
+		"vimdiff a b":              "",
+		"go build ./...":           "",
+		"less /tmp/vim":            "",
+		"make watch":               "",
+	} {
+		t.Run(command, func(t *testing.T) {
+			p := protectedProcess(processes, command)
+			if want == "" {
+				assert.Nil(t, p)
+				return
+			}
+			if assert.NotNil(t, p) {
+				assert.Equal(t, want, p.Name)
+			}
+		})
+	}
+}
+
+func TestCompileProtectedProcesses(t *testing.T) {
+	for name, processes := range map[string][]ProtectedProcess{
+		"without a name":       {{Match: "^vim"}},
+		"malformed pattern":    {{Name: "vim", Match: "^(vim"}},
+		"a negative timeout":   {{Name: "vim", Match: "^vim", Timeout: -time.Second}},
+		"one of the processes": {{Name: "vim", Match: "^vim"}, {Name: "emacs", Match: "^(emacs"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.True(t, errors.Is(compileProtectedProcesses(processes), ErrInvalidProtectedProcess))
+		})
+	}
+
+	t.Run("without a timeout", func(t *testing.T) {
+		processes := []ProtectedProcess{{Name: "vim", Match: "^vim"}}
+		require.NoError(t, compileProtectedProcesses(processes))
+		assert.Equal(t, defaultExitTimeout, processes[0].timeout())
+	})
+}
+
+func TestExitKeys(t *testing.T) {
+	// create a temporary directory
+	dir, err := ioutil.TempDir("", "swm-test-*")
+	require.NoError(t, err)
+
+	// delete it once we are done here
+	defer func() { os.RemoveAll(dir) }()
+
+	// keep the vim sessions within the temporary directory
+	xdg.DataHome = dir
+	defer xdg.Reload()
+
+	tmx := &Manager{}
+	vim := &ProtectedProcess{Name: "vim", ExitKeys: []string{"Escape", ":mksession! " + vimSessionPlaceholder, "Enter"}}
+	sessionName := sanitizeSessionName("github.com/owner1/repo1")
+	sessionPath := path.Join(dir, "swm", "tmux", "swm", "vim", "github.com", "owner1", "repo1")
+	sessions := make(map[string]bool)
+
+	keys, err := tmx.exitKeys(busyPane{target: sessionName + ":0.0", sessionName: sessionName, project: "github.com/owner1/repo1", process: vim}, sessions)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Escape", ":mksession! " + sessionPath + ".vim", "Enter"}, keys)
+	assert.DirExists(t, path.Dir(sessionPath))
+
+	// the other vims of the project are saved alongside
+	keys, err = tmx.exitKeys(busyPane{target: sessionName + ":1.2", sessionName: sessionName, project: "github.com/owner1/repo1", process: vim}, sessions)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Escape", ":mksession! " + sessionPath + ".1.2.vim", "Enter"}, keys)
+
+	// the project of a window of the session of the story
+	keys, err = tmx.exitKeys(busyPane{target: "STORY-123:3.0", sessionName: "STORY-123", project: "github.com/owner2/repo2", process: vim}, sessions)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Escape", ":mksession! " + path.Join(dir, "swm", "tmux", "swm", "vim", "github.com", "owner2", "repo2.vim"), "Enter"}, keys)
+
+	// the keys without a session are sent as is
+	emacs := &ProtectedProcess{Name: "emacs", ExitKeys: []string{"C-x", "C-c"}}
+	keys, err = tmx.exitKeys(busyPane{target: sessionName + ":2.0", sessionName: sessionName, project: "github.com/owner1/repo1", process: emacs}, sessions)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"C-x", "C-c"}, keys)
+}
+
+func TestWaitExit(t *testing.T) {
+	short := &ProtectedProcess{Timeout: 300 * time.Millisecond}
+	long := &ProtectedProcess{Timeout: 600 * time.Millisecond}
+	panes := []busyPane{
+		{target: "a:0.0", process: short},
+		{target: "b:0.0", process: short},
+		{target: "c:0.0", process: long},
+		{target: "d:0.0", process: short},
+	}
+
+	polls := make(map[string]int)
+	running := func(pane busyPane) (bool, error) {
+		polls[pane.target]++
+		switch pane.target {
+		case "a:0.0":
+			// exits on the second poll
+			return polls[pane.target] < 2, nil
+		case "b:0.0", "c:0.0":
+			// refuses to exit
+			return true, nil
+		default:
+			return false, errors.New("ps failed")
+		}
+	}
+
+	start := time.Now()
+	refused := waitExit(panes, running)
+	assert.Equal(t, []busyPane{panes[1], panes[3], panes[2]}, refused)
+	assert.Equal(t, 2, polls["a:0.0"])
+	// each pane is given the timeout of its process
+	assert.True(t, polls["c:0.0"] > polls["b:0.0"])
+	assert.True(t, time.Since(start) >= long.Timeout)
+}
+
+func TestKillServer(t *testing.T) {
+	// create a temporary directory
+	dir, err := ioutil.TempDir("", "swm-test-*")
+	require.NoError(t, err)
+
+	// delete it once we are done here
+	defer func() { os.RemoveAll(dir) }()
+
+	// keep the snapshots within the temporary directory
+	xdg.DataHome = dir
+	defer xdg.Reload()
+
+	processes := []ProtectedProcess{{Name: "vim", Match: "^vim( |$)", ExitKeys: []string{":xa", "Enter"}, Timeout: 200 * time.Millisecond}}
+	require.NoError(t, compileProtectedProcesses(processes))
+	inspector := &FakeProcessInspector{Procs: []Process{
+		{PID: 100, PPID: 1, PGID: 100, TPGID: 200, TTY: "/dev/pts/1", Comm: "zsh", Args: []string{"-zsh"}},
+		{PID: 200, PPID: 100, PGID: 200, TPGID: 200, TTY: "/dev/pts/1", Comm: "vim", Args: []string{"/usr/bin/vim", "main.go"}},
+		{PID: 300, PPID: 1, PGID: 300, TPGID: 300, TTY: "/dev/pts/2", Comm: "zsh", Args: []string{"-zsh"}},
+	}}
+	client := func() *FakeClient {
+		return &FakeClient{Outputs: map[string]string{
+			"list-panes -a -F " + busyPanesFormat: "project\t0\t0\t100\t/dev/pts/1\t\nproject\t1\t0\t300\t/dev/pts/2\t",
+			"list-panes -a -F " + snapshotFormat:  "project\t0\tvim\tb25d,80x24,0,0,2\t1\t0\t/code/project\t1\t100\t/dev/pts/1\t",
+			"list-sessions":                       "project",
+		}}
+	}
+
+	t.Run("a protected process is running", func(t *testing.T) {
+		f := client()
+		tmx := &Manager{client: f, processes: inspector, protectedProcesses: processes}
+		err := tmx.KillServer(false)
+		assert.True(t, errors.Is(err, ErrProtectedProcessFound))
+		assert.Contains(t, err.Error(), "project:0.0 (vim)")
+		assert.NotContains(t, f.Commands, "kill-server")
+	})
+
+	t.Run("the protected process refuses to exit", func(t *testing.T) {
+		f := client()
+		tmx := &Manager{client: f, processes: inspector, protectedProcesses: processes}
+		err := tmx.KillServer(true)
+		assert.True(t, errors.Is(err, ErrProtectedProcessRefusedToExit))
+		assert.Contains(t, f.Commands, "send-keys -t project:0.0 :xa")
+		assert.NotContains(t, f.Commands, "kill-server")
+		// the sessions were saved
+		assert.FileExists(t, path.Join(dir, "swm", "tmux", "swm", "snapshot.json"))
+	})
+
+	t.Run("the protected process exits", func(t *testing.T) {
+		f := client()
+		// the shell is back in the foreground
+		shell := inspector.Procs[0]
+		shell.TPGID = 100
+		exited := &FakeProcessInspector{Procs: []Process{shell, inspector.Procs[2]}}
+		tmx := &Manager{client: f, processes: inspector, protectedProcesses: processes}
+		panes, err := tmx.getBusyPanes(processes)
+		require.NoError(t, err)
+		require.Len(t, panes, 1)
+		assert.Equal(t, "/usr/bin/vim main.go", panes[0].command)
+
+		tmx.processes = exited
+		require.NoError(t, tmx.exitProcesses(panes))
+		require.NoError(t, tmx.KillServer(false))
+		assert.Equal(t, "kill-server", f.Commands[len(f.Commands)-1])
+	})
+}
@@ -0,0 +1,189 @@
+package tmux
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/kalbasit/swm/history"
+	"github.com/kalbasit/swm/ifaces"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// runningMark prefixes the sessions that are running in the picker.
+const runningMark = "* "
+
+// RecentProject is a project ranked by Recent.
+type RecentProject struct {
+	Project ifaces.Project
+	Running bool
+	Score   float64
+}
+
+// Recent returns the projects with a running session followed by the
+// projects that were visited, ordered by frecency.
+func (t *Manager) Recent() ([]RecentProject, error) {
+	sessionNameProjects, err := t.getSessionNameProjects()
+	if err != nil {
+		return nil, err
+	}
+	running := t.runningSessionNames()
+
+	// the projects are scored by the history of the story, the global one
+	// outside of any story
+	var storyName string
+	if t.story != nil {
+		storyName = t.story.GetName()
+	}
+	storyHistory, err := history.Load(storyName)
+	if err != nil {
+		return nil, errors.Wrap(err, "error loading the history of the story")
+	}
+
+	var res []RecentProject
+	for _, name := range t.rankSessionNames(sessionNameProjects, running, t.histories()) {
+		prj := sessionNameProjects[name]
+		rp := RecentProject{Project: prj, Running: running[name], Score: storyHistory.Score(prj.String())}
+		if !rp.Running && rp.Score == 0 {
+			continue
+		}
+		res = append(res, rp)
+	}
+
+	return res, nil
+}
+
+// rankSessionNames returns the session names with the running sessions first,
+// the sessions are ordered by the frecency of their project.
+func (t *Manager) rankSessionNames(sessionNameProjects map[string]ifaces.Project, running map[string]bool, histories []*history.History) []string {
+	importPathNames := make(map[string]string, len(sessionNameProjects))
+	importPaths := make([]string, 0, len(sessionNameProjects))
+	for name, prj := range sessionNameProjects {
+		importPathNames[prj.String()] = name
+		importPaths = append(importPaths, prj.String())
+	}
+	sort.Strings(importPaths)
+
+	var names, idle []string
+	for _, ip := range history.Rank(importPaths, histories...) {
+		if name := importPathNames[ip]; running[name] {
+			names = append(names, name)
+		} else {
+			idle = append(idle, name)
+		}
+	}
+
+	return append(names, idle...)
+}
+
+// runningSessions returns the names of the sessions running on the tmux
+// server of the story.
+func (t *Manager) runningSessions() map[string]bool {
+	running := make(map[string]bool)
+	for _, name := range t.tmuxSessions() {
+		running[name] = true
+	}
+
+	return running
+}
+
+// runningSessionNames returns the names of the running sessions, as given by
+// SessionName, of the projects of the story. On tmux, the projects of the
+// running sessions are found by their project option.
+func (t *Manager) runningSessionNames() map[string]bool {
+	if mux := t.multiplexer(); mux != ifaces.Multiplexer(t) {
+		return runningSessionsOf(mux)
+	}
+
+	running := make(map[string]bool)
+	for _, importPath := range t.runningProjects() {
+		running[t.sessionNameOf(importPath)] = true
+	}
+
+	return running
+}
+
+// runningSessionsOf returns the names of the sessions of the story running on
+// the multiplexer.
+func runningSessionsOf(mux ifaces.Multiplexer) map[string]bool {
+	running := make(map[string]bool)
+
+	names, err := mux.Sessions()
+	if err != nil {
+		log.Debug().Err(err).Msg("error listing the running sessions")
+		return running
+	}
+	for _, name := range names {
+		running[name] = true
+	}
+
+	return running
+}
+
+// Sessions returns the names of the sessions running on the tmux server of
+// the story, none if the server is not running. In WindowPerProject, the
+// windows of the session of the story are the sessions of the projects.
+func (t *Manager) Sessions() ([]string, error) {
+	if t.windowPerProject() {
+		var names []string
+		for name := range t.runningProjects() {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		return names, nil
+	}
+
+	return t.tmuxSessions(), nil
+}
+
+// tmuxSessions returns the names of the sessions running on the tmux server
+// of the story.
+func (t *Manager) tmuxSessions() []string {
+	out, err := t.client.Run("list-sessions", "-F", "#{session_name}")
+	if err != nil {
+		// the server is not running
+		return nil
+	}
+
+	var names []string
+	for _, name := range strings.Split(out, "\n") {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// histories returns the history of the story, if any, followed by the global
+// history.
+func (t *Manager) histories() []*history.History {
+	var names []string
+	if t.story != nil {
+		names = append(names, t.story.GetName())
+	}
+	names = append(names, "")
+
+	var res []*history.History
+	for _, name := range names {
+		h, err := history.Load(name)
+		if err != nil {
+			log.Warn().Err(err).Str("story-name", name).Msg("error loading the history, ignoring it")
+			continue
+		}
+		res = append(res, h)
+	}
+
+	return res
+}
+
+// recordVisit records the visit of the project in the histories.
+func (t *Manager) recordVisit(prj ifaces.Project) {
+	for _, h := range t.histories() {
+		h.Record(prj.String())
+		if err := h.Save(); err != nil {
+			log.Warn().Err(err).Msg("error saving the history")
+		}
+	}
+}
@@ -0,0 +1,289 @@
+package tmux
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// Server is a tmux server of swm, the one of a story or the one outside of
+// any story.
+type Server struct {
+	// Socket is the name of the socket of the server.
+	Socket string `json:"socket"`
+
+	// Story is the name of the story of the server, it's empty for the server
+	// outside of any story or if the story is not known.
+	Story string `json:"story,omitempty"`
+
+	// Running is false if the socket was left behind by a server that is no
+	// longer running.
+	Running bool `json:"running"`
+
+	Sessions []*Session `json:"sessions"`
+}
+
+// Session is a session running on a tmux server.
+type Session struct {
+	Name string `json:"name"`
+
+	// Project is the import path of the project of the session.
+	Project string `json:"project"`
+
+	// Clients is the number of clients attached to the session.
+	Clients int `json:"clients"`
+
+	Windows      int       `json:"windows"`
+	LastActivity time.Time `json:"last_activity"`
+
+	// BusyPanes are the panes running a protected process in the foreground.
+	BusyPanes []*BusyPane `json:"busy_panes,omitempty"`
+}
+
+// BusyPane is a pane running a protected process in the foreground.
+type BusyPane struct {
+	// Pane is the index of the window and of the pane, such as 1.0.
+	Pane string `json:"pane"`
+
+	// Process is the name of the protected process.
+	Process string `json:"process"`
+
+	Command string `json:"command"`
+}
+
+// sessionsFormat are the fields of the sessions listed by tmux, separated by
+// tabs.
+var sessionsFormat = strings.Join([]string{
+	"#{session_name}",
+	"#{session_attached}",
+	"#{session_windows}",
+	"#{session_activity}",
+	"#{" + projectOption + "}",
+}, "\t")
+
+// ListServers returns the tmux servers of swm found in the socket directory
+// of tmux, or the one of WithSocketDir, the server outside of any story
+// first. The stories are given by name to tell which story a server belongs
+// to. Only the socket directory, the protected processes and the process
+// inspector of the options are used.
+func ListServers(storyNames []string, opts ...Option) ([]*Server, error) {
+	m := &Manager{}
+	for _, opt := range opts {
+		opt(m)
+	}
+	if m.processes == nil {
+		m.processes = defaultProcessInspector()
+	}
+	if m.protectedProcesses == nil {
+		m.protectedProcesses = DefaultProtectedProcesses()
+	}
+	if err := compileProtectedProcesses(m.protectedProcesses); err != nil {
+		return nil, err
+	}
+
+	dir := m.socketDir
+	if dir == "" {
+		dir = socketDir()
+	}
+	sockets, err := socketNames(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(sockets) == 0 {
+		return nil, nil
+	}
+
+	stories := make(map[string]string, len(storyNames))
+	for _, name := range storyNames {
+		stories[socketNameFor(name)] = name
+	}
+
+	// the processes are read once for all the servers
+	table, err := m.processes.Processes()
+	if err != nil {
+		return nil, err
+	}
+	m.processes = &processTableInspector{table: table}
+
+	servers := make([]*Server, 0, len(sockets))
+	for _, socket := range sockets {
+		// a control-mode client would be counted as attached to a session
+		m.client = &execClient{flags: m.socketFlags(socket), env: m.clientEnv()}
+		srv, err := m.server(socket)
+		if err != nil {
+			return nil, err
+		}
+		srv.Story = stories[socket]
+		servers = append(servers, srv)
+	}
+
+	return servers, nil
+}
+
+// LastActivity returns the last activity of the sessions of the server, it's
+// zero if the server has no session.
+func (s *Server) LastActivity() time.Time {
+	var last time.Time
+	for _, session := range s.Sessions {
+		if session.LastActivity.After(last) {
+			last = session.LastActivity
+		}
+	}
+
+	return last
+}
+
+// Attached returns true if a client is attached to a session of the server.
+func (s *Server) Attached() bool {
+	for _, session := range s.Sessions {
+		if session.Clients > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IdleServers returns the running servers of the stories whose sessions had
+// no activity for the idle duration. The server outside of any story and the
+// servers of unknown stories are never idle, nor are the servers with an
+// attached client unless attached is true.
+func IdleServers(servers []*Server, idle time.Duration, attached bool) []*Server {
+	cutoff := nowFn().Add(-idle)
+
+	var res []*Server
+	for _, srv := range servers {
+		if !srv.Running || srv.Story == "" || !srv.LastActivity().Before(cutoff) {
+			continue
+		}
+		if srv.Attached() && !attached {
+			continue
+		}
+		res = append(res, srv)
+	}
+
+	return res
+}
+
+// server returns the server of the socket with its sessions.
+func (t *Manager) server(socket string) (*Server, error) {
+	srv := &Server{Socket: socket, Sessions: []*Session{}}
+
+	out, err := t.client.Run("list-sessions", "-F", sessionsFormat)
+	if err != nil {
+		log.Debug().Err(err).Str("socket", socket).Msg("the tmux server is not running")
+		return srv, nil
+	}
+	srv.Running = true
+
+	sessions := make(map[string]*Session)
+	for _, line := range strings.Split(out, "\n") {
+		session, err := parseSession(line)
+		if err != nil {
+			return nil, err
+		}
+		if session != nil {
+			srv.Sessions = append(srv.Sessions, session)
+			sessions[session.Name] = session
+		}
+	}
+
+	panes, err := t.getBusyPanes(t.protectedProcesses)
+	if err != nil {
+		return nil, err
+	}
+	for _, pane := range panes {
+		if session, ok := sessions[pane.sessionName]; ok {
+			session.BusyPanes = append(session.BusyPanes, &BusyPane{
+				Pane:    strings.TrimPrefix(pane.target, pane.sessionName+":"),
+				Process: pane.process.Name,
+				Command: pane.command,
+			})
+		}
+	}
+
+	return srv, nil
+}
+
+// parseSession parses a session listed with sessionsFormat, it returns nil
+// for an empty line.
+func parseSession(line string) (*Session, error) {
+	if line == "" {
+		return nil, nil
+	}
+	fields := strings.Split(line, "\t")
+	if len(fields) != 5 {
+		return nil, errors.Errorf("unexpected session %q", line)
+	}
+
+	ints := make([]int64, 3)
+	for i := range ints {
+		n, err := strconv.ParseInt(fields[i+1], 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error parsing the session %q", line)
+		}
+		ints[i] = n
+	}
+
+	return &Session{
+		Name:         fields[0],
+		Project:      optionProject(fields[4], fields[0]),
+		Clients:      int(ints[0]),
+		Windows:      int(ints[1]),
+		LastActivity: time.Unix(ints[2], 0),
+	}, nil
+}
+
+// socketDir returns the directory of the sockets named with tmux -L, tmux
+// ignores TMPDIR.
+func socketDir() string {
+	dir := os.Getenv("TMUX_TMPDIR")
+	if dir == "" {
+		dir = "/tmp"
+	}
+
+	return path.Join(dir, fmt.Sprintf("tmux-%d", os.Getuid()))
+}
+
+// socketNames returns the names of the sockets of swm in the directory,
+// sorted.
+func socketNames(dir string) ([]string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		// no tmux server was ever started
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "error listing the tmux sockets in %s", dir)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.Mode()&os.ModeSocket == 0 {
+			continue
+		}
+		if name := entry.Name(); name == socketNameFor("") || strings.HasPrefix(name, socketNameFor("")+"-") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// processTableInspector returns the same processes on every call.
+type processTableInspector struct {
+	table ProcessTable
+}
+
+func (i *processTableInspector) Processes() (ProcessTable, error) {
+	return i.table, nil
+}
@@ -0,0 +1,127 @@
+package tmux
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSession(t *testing.T) {
+	s, err := parseSession("github" + dotChar + "com/owner1/repo1\t2\t3\t1600000000\t")
+	require.NoError(t, err)
+	assert.Equal(t, &Session{
+		Name:         "github" + dotChar + "com/owner1/repo1",
+		Project:      "github.com/owner1/repo1",
+		Clients:      2,
+		Windows:      3,
+		LastActivity: time.Unix(1600000000, 0),
+	}, s)
+
+	s, err = parseSession("repo1\t0\t1\t1600000000\tgithub.com/owner1/repo1")
+	require.NoError(t, err)
+	assert.Equal(t, "repo1", s.Name)
+	assert.Equal(t, "github.com/owner1/repo1", s.Project)
+
+	s, err = parseSession("")
+	require.NoError(t, err)
+	assert.Nil(t, s)
+
+	_, err = parseSession("project\t2\t3")
+	assert.Error(t, err)
+	_, err = parseSession("project\tone\t3\t1600000000\t")
+	assert.Error(t, err)
+}
+
+func TestServer(t *testing.T) {
+	processes := []ProtectedProcess{{Name: "vim", Match: "^vim( |$)"}}
+	require.NoError(t, compileProtectedProcesses(processes))
+	inspector := &FakeProcessInspector{Procs: []Process{
+		{PID: 100, PPID: 1, PGID: 100, TPGID: 200, TTY: "/dev/pts/1", Comm: "zsh", Args: []string{"-zsh"}},
+		{PID: 200, PPID: 100, PGID: 200, TPGID: 200, TTY: "/dev/pts/1", Comm: "vim", Args: []string{"vim", "main.go"}},
+	}}
+
+	t.Run("running", func(t *testing.T) {
+		f := &FakeClient{Outputs: map[string]string{
+			"list-sessions -F " + sessionsFormat:  "project1\t1\t2\t1600000000\t\nproject2\t0\t1\t1600000100\t",
+			"list-panes -a -F " + busyPanesFormat: "project1\t0\t0\t300\t/dev/pts/2\t\nproject2\t1\t0\t100\t/dev/pts/1\t",
+		}}
+		tmx := &Manager{client: f, processes: inspector, protectedProcesses: processes}
+		srv, err := tmx.server("swm-STORY-123")
+		require.NoError(t, err)
+		assert.True(t, srv.Running)
+		assert.Equal(t, "swm-STORY-123", srv.Socket)
+		require.Len(t, srv.Sessions, 2)
+		assert.Equal(t, "project1", srv.Sessions[0].Name)
+		assert.Equal(t, 1, srv.Sessions[0].Clients)
+		assert.Empty(t, srv.Sessions[0].BusyPanes)
+		assert.Equal(t, []*BusyPane{{Pane: "1.0", Process: "vim", Command: "vim main.go"}}, srv.Sessions[1].BusyPanes)
+	})
+
+	t.Run("not running", func(t *testing.T) {
+		f := &FakeClient{Errors: map[string]error{"list-sessions": errors.New("no server running")}}
+		tmx := &Manager{client: f, processes: inspector, protectedProcesses: processes}
+		srv, err := tmx.server("swm")
+		require.NoError(t, err)
+		assert.False(t, srv.Running)
+		assert.Empty(t, srv.Sessions)
+	})
+}
+
+func TestSocketNames(t *testing.T) {
+	// create a temporary directory
+	dir, err := ioutil.TempDir("", "swm-test-*")
+	require.NoError(t, err)
+
+	// delete it once we are done here
+	defer func() { os.RemoveAll(dir) }()
+
+	for _, name := range []string{"swm-STORY-123", "swm", "default", "swmx"} {
+		l, err := net.Listen("unix", path.Join(dir, name))
+		require.NoError(t, err)
+		defer l.Close()
+	}
+	// not a socket
+	require.NoError(t, ioutil.WriteFile(path.Join(dir, "swm-file"), nil, 0644))
+
+	names, err := socketNames(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"swm", "swm-STORY-123"}, names)
+
+	names, err = socketNames(path.Join(dir, "missing"))
+	require.NoError(t, err)
+	assert.Empty(t, names)
+}
+
+func TestIdleServers(t *testing.T) {
+	now := time.Date(2020, 9, 13, 12, 0, 0, 0, time.UTC)
+	nowFn = func() time.Time { return now }
+	defer func() { nowFn = time.Now }()
+
+	session := func(ago time.Duration) *Session { return &Session{LastActivity: now.Add(-ago)} }
+	servers := []*Server{
+		{Socket: "swm", Running: true, Sessions: []*Session{session(100 * time.Hour)}},
+		{Socket: "swm-idle", Story: "idle", Running: true, Sessions: []*Session{session(100 * time.Hour), session(73 * time.Hour)}},
+		{Socket: "swm-active", Story: "active", Running: true, Sessions: []*Session{session(100 * time.Hour), session(time.Hour)}},
+		{Socket: "swm-stopped", Story: "stopped"},
+		{Socket: "swm-unknown", Running: true, Sessions: []*Session{session(100 * time.Hour)}},
+		{Socket: "swm-attached", Story: "attached", Running: true, Sessions: []*Session{session(100 * time.Hour), {Clients: 1, LastActivity: now.Add(-80 * time.Hour)}}},
+	}
+
+	idle := IdleServers(servers, 72*time.Hour, false)
+	require.Len(t, idle, 1)
+	assert.Equal(t, "swm-idle", idle[0].Socket)
+	assert.Equal(t, now.Add(-73*time.Hour), idle[0].LastActivity())
+
+	// the servers with an attached client are only reaped on request
+	idle = IdleServers(servers, 72*time.Hour, true)
+	require.Len(t, idle, 2)
+	assert.Equal(t, "swm-idle", idle[0].Socket)
+	assert.Equal(t, "swm-attached", idle[1].Socket)
+}
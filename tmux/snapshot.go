@@ -0,0 +1,454 @@
+package tmux
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adrg/xdg"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// ErrNoSnapshot is returned by Restore if no snapshot was saved.
+var ErrNoSnapshot = errors.New("no snapshot was saved")
+
+// defaultRestoreCommands are the programs started again in the restored
+// panes, the other commands are not run to avoid side effects.
+var defaultRestoreCommands = []string{"vi", "vim", "nvim", "emacs", "man", "less", "more", "tail", "top", "htop", "watch", "ssh", "mosh", "psql", "mysql", "sqlite3", "irb", "python", "python3", "node"}
+
+var nowFn = time.Now
+
+// Snapshot is the state of the sessions of a tmux server.
+type Snapshot struct {
+	CreatedAt time.Time          `json:"created_at"`
+	Sessions  []*SessionSnapshot `json:"sessions"`
+}
+
+// SessionSnapshot is the state of a session.
+type SessionSnapshot struct {
+	Name    string            `json:"name"`
+	Windows []*WindowSnapshot `json:"windows"`
+
+	// Project is the import path of the project of the session. It's empty
+	// for the session of the story.
+	Project string `json:"project,omitempty"`
+}
+
+// WindowSnapshot is the state of a window.
+type WindowSnapshot struct {
+	Index  int             `json:"index"`
+	Name   string          `json:"name"`
+	Layout string          `json:"layout"`
+	Active bool            `json:"active"`
+	Panes  []*PaneSnapshot `json:"panes"`
+
+	// Project is the import path of the project of the window, in the
+	// session of the story. It's empty for the windows of the session of a
+	// project.
+	Project string `json:"project,omitempty"`
+}
+
+// PaneSnapshot is the state of a pane.
+type PaneSnapshot struct {
+	Index  int    `json:"index"`
+	Path   string `json:"path"`
+	Active bool   `json:"active"`
+
+	// Command is the command running in the foreground of the pane, it's
+	// empty if the shell of the pane is in the foreground.
+	Command string `json:"command,omitempty"`
+
+	// the pid of the shell and the tty of the pane, they are only needed to
+	// find the foreground command.
+	pid int
+	tty string
+}
+
+// WithRestoreCommands sets the programs started again in the restored panes.
+func WithRestoreCommands(commands ...string) Option {
+	return func(m *Manager) { m.restoreCommands = commands }
+}
+
+// WithRestorePrompt sets the function asked if the saved sessions should be
+// restored when the first session of the server is started. The sessions
+// are not restored automatically by default.
+func WithRestorePrompt(prompt func(*Snapshot) (bool, error)) Option {
+	return func(m *Manager) { m.restorePrompt = prompt }
+}
+
+// dataDir returns the directory of the snapshot and of the vim session files
+// of the tmux server of the story, the server outside of any story if the
+// name is empty.
+func dataDir(storyName string) string {
+	return path.Join(xdg.DataHome, "swm", "tmux", socketNameFor(storyName))
+}
+
+// RemoveData removes the snapshot and the vim session files of the tmux
+// server of the story, once the story is removed.
+func RemoveData(storyName string) error {
+	return os.RemoveAll(dataDir(storyName))
+}
+
+// snapshotPath returns the path of the snapshot of the tmux server.
+func (t *Manager) snapshotPath() string {
+	return path.Join(t.dataDir(), "snapshot.json")
+}
+
+// dataDir returns the directory of the snapshot and of the vim session files
+// of the tmux server.
+func (t *Manager) dataDir() string {
+	if t.story != nil {
+		return dataDir(t.story.GetName())
+	}
+
+	return dataDir("")
+}
+
+// Save saves the state of the sessions of the tmux server, it returns the
+// snapshot that was saved.
+func (t *Manager) Save() (*Snapshot, error) {
+	s, err := t.snapshot()
+	if err != nil {
+		return nil, err
+	}
+	if err := t.saveSnapshot(s); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Restore starts the saved sessions that are not running, it returns the
+// names of the sessions that were started.
+func (t *Manager) Restore() ([]string, error) {
+	s, err := t.LoadSnapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	return t.restore(s, t.runningSessions())
+}
+
+// LoadSnapshot returns the saved snapshot of the tmux server.
+func (t *Manager) LoadSnapshot() (*Snapshot, error) {
+	f, err := os.Open(t.snapshotPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNoSnapshot
+		}
+		return nil, errors.Wrap(err, "error opening the snapshot")
+	}
+	defer f.Close()
+
+	var s Snapshot
+	if err := json.NewDecoder(f).Decode(&s); err != nil {
+		return nil, errors.Wrap(err, "error decoding the snapshot")
+	}
+
+	return &s, nil
+}
+
+func (t *Manager) saveSnapshot(s *Snapshot) error {
+	p := t.snapshotPath()
+	if err := os.MkdirAll(path.Dir(p), 0755); err != nil {
+		return errors.Wrap(err, "error creating the parent directory of the snapshot")
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "error encoding the snapshot")
+	}
+
+	// write the snapshot atomically to never leave a truncated one behind
+	f, err := os.OpenFile(p+".tmp", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return errors.Wrap(err, "error opening the snapshot")
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return errors.Wrap(err, "error writing the snapshot")
+	}
+	if err := f.Close(); err != nil {
+		return errors.Wrap(err, "error writing the snapshot")
+	}
+
+	return os.Rename(p+".tmp", p)
+}
+
+// snapshotFormat are the fields of the panes listed by tmux, separated by
+// tabs.
+var snapshotFormat = strings.Join([]string{
+	"#{session_name}",
+	"#{window_index}",
+	"#{window_name}",
+	"#{window_layout}",
+	"#{window_active}",
+	"#{pane_index}",
+	"#{pane_current_path}",
+	"#{pane_active}",
+	"#{pane_pid}",
+	"#{pane_tty}",
+	"#{" + projectOption + "}",
+}, "\t")
+
+// snapshot returns the state of the sessions of the tmux server.
+func (t *Manager) snapshot() (*Snapshot, error) {
+	out, err := t.client.Run("list-panes", "-a", "-F", snapshotFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := parseSnapshot(out)
+	if err != nil {
+		return nil, err
+	}
+	for _, session := range s.Sessions {
+		out, err := t.client.Run("show-options", "-q", "-v", "-t", "="+session.Name+":", projectOption)
+		if err != nil {
+			return nil, err
+		}
+		session.Project = strings.TrimSpace(out)
+		// the windows inherit the project of the session
+		for _, w := range session.Windows {
+			if w.Project == session.Project {
+				w.Project = ""
+			}
+		}
+	}
+
+	table, err := t.processes.Processes()
+	if err != nil {
+		log.Warn().Err(err).Msg("error finding the commands of the panes")
+		return s, nil
+	}
+	for _, session := range s.Sessions {
+		for _, w := range session.Windows {
+			for _, p := range w.Panes {
+				if fg := table.Foreground(p.tty, p.pid); fg != nil {
+					p.Command = fg.CommandLine()
+				}
+			}
+		}
+	}
+
+	return s, nil
+}
+
+// parseSnapshot parses the panes listed by tmux with the snapshotFormat.
+func parseSnapshot(out string) (*Snapshot, error) {
+	s := &Snapshot{CreatedAt: nowFn()}
+
+	sessions := make(map[string]*SessionSnapshot)
+	windows := make(map[string]*WindowSnapshot)
+	for _, line := range strings.Split(out, "\n") {
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != 11 {
+			return nil, errors.Errorf("unexpected pane listed by tmux: %q", line)
+		}
+		windowIndex, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, errors.Wrapf(err, "error parsing the window index of %q", line)
+		}
+		paneIndex, err := strconv.Atoi(fields[5])
+		if err != nil {
+			return nil, errors.Wrapf(err, "error parsing the pane index of %q", line)
+		}
+		pid, err := strconv.Atoi(fields[8])
+		if err != nil {
+			return nil, errors.Wrapf(err, "error parsing the pane pid of %q", line)
+		}
+
+		session, ok := sessions[fields[0]]
+		if !ok {
+			session = &SessionSnapshot{Name: fields[0]}
+			sessions[fields[0]] = session
+			s.Sessions = append(s.Sessions, session)
+		}
+		w, ok := windows[fields[0]+"\t"+fields[1]]
+		if !ok {
+			w = &WindowSnapshot{Index: windowIndex, Name: fields[2], Layout: fields[3], Active: fields[4] == "1", Project: fields[10]}
+			windows[fields[0]+"\t"+fields[1]] = w
+			session.Windows = append(session.Windows, w)
+		}
+		w.Panes = append(w.Panes, &PaneSnapshot{
+			Index:  paneIndex,
+			Path:   fields[6],
+			Active: fields[7] == "1",
+			pid:    pid,
+			tty:    fields[9],
+		})
+	}
+
+	for _, session := range s.Sessions {
+		sort.Slice(session.Windows, func(i, j int) bool { return session.Windows[i].Index < session.Windows[j].Index })
+		for _, w := range session.Windows {
+			sort.Slice(w.Panes, func(i, j int) bool { return w.Panes[i].Index < w.Panes[j].Index })
+		}
+	}
+
+	return s, nil
+}
+
+// restore creates the sessions of the snapshot, except the running ones.
+func (t *Manager) restore(s *Snapshot, running map[string]bool) ([]string, error) {
+	var restored []string
+	for _, session := range s.Sessions {
+		if running[session.Name] || len(session.Windows) == 0 {
+			continue
+		}
+		if err := t.restoreSession(session); err != nil {
+			return restored, errors.Wrapf(err, "error restoring the session %s", session.Name)
+		}
+		restored = append(restored, session.Name)
+	}
+
+	return restored, nil
+}
+
+func (t *Manager) restoreSession(session *SessionSnapshot) error {
+	var activeWindow string
+	for _, w := range session.Windows {
+		if len(w.Panes) == 0 {
+			continue
+		}
+		created := activeWindow != ""
+		target := session.Name + ":" + strconv.Itoa(w.Index)
+
+		var args []string
+		if !created {
+			args = append(args, "new-session", "-d", "-s", session.Name)
+		} else {
+			args = append(args, "new-window", "-d", "-t", target)
+		}
+		args = append(args, "-c", w.Panes[0].Path, "-n", w.Name, "-P", "-F", "#{window_id} #{pane_id} #{window_index}")
+		out, err := t.client.Run(args...)
+		if err != nil {
+			return err
+		}
+		ids := strings.Fields(out)
+		if len(ids) != 3 {
+			return errors.Errorf("unexpected output of tmux creating a window: %q", out)
+		}
+		windowID, paneID := ids[0], ids[1]
+
+		if !created {
+			// the first window is created at the base index, move it to its index
+			if ids[2] != strconv.Itoa(w.Index) {
+				if _, err := t.client.Run("move-window", "-s", windowID, "-t", target); err != nil {
+					return err
+				}
+			}
+			if err := t.setSessionEnv(session.Name, nil); err != nil {
+				return err
+			}
+			if err := t.setStatusOptions(); err != nil {
+				return err
+			}
+			if session.Project != "" {
+				if _, err := t.client.Run("set-option", "-t", "="+session.Name+":", projectOption, session.Project); err != nil {
+					return err
+				}
+			}
+		}
+		if w.Project != "" {
+			if _, err := t.client.Run("set-option", "-w", "-t", windowID, projectOption, w.Project); err != nil {
+				return err
+			}
+		}
+
+		paneIDs := []string{paneID}
+		for _, p := range w.Panes[1:] {
+			paneID, err := t.client.Run("split-window", "-d", "-t", windowID, "-c", p.Path, "-P", "-F", "#{pane_id}")
+			if err != nil {
+				return err
+			}
+			paneIDs = append(paneIDs, paneID)
+		}
+		if _, err := t.client.Run("select-layout", "-t", windowID, w.Layout); err != nil {
+			return err
+		}
+
+		for j, p := range w.Panes {
+			if t.shouldRestoreCommand(p.Command) {
+				command := p.Command
+				// reopen the session vim saved when it exited
+				if sessionPath := t.vimSessionPath(optionProject(w.Project, session.Name)); fileExists(sessionPath) {
+					command, _ = withVimSession(command, sessionPath)
+				}
+				if err := t.sendCommand(paneIDs[j], command); err != nil {
+					return err
+				}
+			}
+			if p.Active {
+				if _, err := t.client.Run("select-pane", "-t", paneIDs[j]); err != nil {
+					return err
+				}
+			}
+		}
+
+		if w.Active || activeWindow == "" {
+			activeWindow = windowID
+		}
+	}
+
+	_, err := t.client.Run("select-window", "-t", activeWindow)
+
+	return err
+}
+
+// shouldRestoreCommand returns true if the program of the command is one of
+// the restore commands.
+func (t *Manager) shouldRestoreCommand(command string) bool {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return false
+	}
+
+	commands := t.restoreCommands
+	if commands == nil {
+		commands = defaultRestoreCommands
+	}
+	for _, c := range commands {
+		if path.Base(fields[0]) == c {
+			return true
+		}
+	}
+
+	return false
+}
+
+// offerRestore restores the saved sessions if the server is not running and
+// the restore prompt accepts the snapshot.
+func (t *Manager) offerRestore() error {
+	if t.restorePrompt == nil || len(t.runningSessions()) > 0 {
+		return nil
+	}
+
+	s, err := t.LoadSnapshot()
+	if err != nil {
+		if errors.Is(err, ErrNoSnapshot) {
+			return nil
+		}
+		return err
+	}
+	if len(s.Sessions) == 0 {
+		return nil
+	}
+
+	ok, err := t.restorePrompt(s)
+	if err != nil || !ok {
+		return err
+	}
+
+	_, err = t.restore(s, nil)
+
+	return err
+}
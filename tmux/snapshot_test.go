@@ -0,0 +1,223 @@
+package tmux
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adrg/xdg"
+	"github.com/kalbasit/swm/story"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSnapshot(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	nowFn = func() time.Time { return now }
+	defer func() { nowFn = time.Now }()
+
+	line := func(fields ...string) string { return strings.Join(fields, "\t") }
+	out := strings.Join([]string{
+		line("project", "1", "shell", "b25d,80x24,0,0,2", "1", "0", "/code/project", "1", "102", "/dev/pts/2", ""),
+		line("project", "0", "editor", "c3a1,80x24,0,0{40x24,0,0,0,39x24,41,0,1}", "0", "1", "/code/project/web", "0", "101", "/dev/pts/1", ""),
+		line("project", "0", "editor", "c3a1,80x24,0,0{40x24,0,0,0,39x24,41,0,1}", "0", "0", "/code/project", "1", "100", "/dev/pts/0", ""),
+		line("STORY-123", "0", "other", "b25d,80x24,0,0,3", "1", "0", "/code/other", "1", "103", "/dev/pts/3", "github.com/owner/other"),
+		"",
+	}, "\n")
+
+	s, err := parseSnapshot(out)
+	require.NoError(t, err)
+	assert.Equal(t, &Snapshot{
+		CreatedAt: now,
+		Sessions: []*SessionSnapshot{
+			{
+				Name: "project",
+				Windows: []*WindowSnapshot{
+					{
+						Index:  0,
+						Name:   "editor",
+						Layout: "c3a1,80x24,0,0{40x24,0,0,0,39x24,41,0,1}",
+						Panes: []*PaneSnapshot{
+							{Index: 0, Path: "/code/project", Active: true, pid: 100, tty: "/dev/pts/0"},
+							{Index: 1, Path: "/code/project/web", pid: 101, tty: "/dev/pts/1"},
+						},
+					},
+					{
+						Index:  1,
+						Name:   "shell",
+						Layout: "b25d,80x24,0,0,2",
+						Active: true,
+						Panes:  []*PaneSnapshot{{Index: 0, Path: "/code/project", Active: true, pid: 102, tty: "/dev/pts/2"}},
+					},
+				},
+			},
+			{
+				Name: "STORY-123",
+				Windows: []*WindowSnapshot{{
+					Index:   0,
+					Name:    "other",
+					Layout:  "b25d,80x24,0,0,3",
+					Active:  true,
+					Panes:   []*PaneSnapshot{{Index: 0, Path: "/code/other", Active: true, pid: 103, tty: "/dev/pts/3"}},
+					Project: "github.com/owner/other",
+				}},
+			},
+		},
+	}, s)
+
+	t.Run("a malformed line", func(t *testing.T) {
+		_, err := parseSnapshot("project\t0")
+		assert.Error(t, err)
+	})
+}
+
+func TestSnapshot(t *testing.T) {
+	line := func(fields ...string) string { return strings.Join(fields, "\t") }
+	f := &FakeClient{Outputs: map[string]string{
+		"list-panes -a -F " + snapshotFormat: strings.Join([]string{
+			line("project", "0", "zsh", "b25d,80x24,0,0,2", "1", "0", "/code/project", "1", "100", "/dev/pts/0", "github.com/owner/project"),
+			line("STORY-123", "0", "other", "b25d,80x24,0,0,3", "1", "0", "/code/other", "1", "103", "/dev/pts/3", "github.com/owner/other"),
+		}, "\n"),
+		"show-options -q -v -t =project: " + projectOption: "github.com/owner/project\n",
+	}}
+	tmx := &Manager{client: f, processes: &FakeProcessInspector{}}
+
+	s, err := tmx.snapshot()
+	require.NoError(t, err)
+	require.Len(t, s.Sessions, 2)
+
+	// the project of the session is not repeated on its windows
+	assert.Equal(t, "github.com/owner/project", s.Sessions[0].Project)
+	assert.Empty(t, s.Sessions[0].Windows[0].Project)
+
+	assert.Empty(t, s.Sessions[1].Project)
+	assert.Equal(t, "github.com/owner/other", s.Sessions[1].Windows[0].Project)
+}
+
+func TestRestore(t *testing.T) {
+	s := &Snapshot{
+		Sessions: []*SessionSnapshot{
+			{
+				Name:    "project",
+				Project: "github.com/owner/project",
+				Windows: []*WindowSnapshot{
+					{
+						Index:  1,
+						Name:   "editor",
+						Layout: "c3a1,80x24,0,0{40x24,0,0,0,39x24,41,0,1}",
+						Panes: []*PaneSnapshot{
+							{Index: 0, Path: "/code/project", Command: "vim main.go"},
+							{Index: 1, Path: "/code/project/web", Active: true, Command: "make deploy"},
+						},
+					},
+					{
+						Index:  2,
+						Name:   "shell",
+						Layout: "b25d,80x24,0,0,2",
+						Active: true,
+						Panes:  []*PaneSnapshot{{Index: 0, Path: "/code/project", Active: true}},
+					},
+				},
+			},
+			{
+				Name:    "running",
+				Windows: []*WindowSnapshot{{Name: "zsh", Panes: []*PaneSnapshot{{Path: "/code/running"}}}},
+			},
+		},
+	}
+
+	st, err := story.New("STORY-123", "")
+	require.NoError(t, err)
+
+	f := &FakeClient{}
+	tmx := &Manager{story: st, client: f}
+	restored, err := tmx.restore(s, map[string]bool{"running": true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"project"}, restored)
+	assert.Equal(t, []string{
+		"new-session -d -s project -c /code/project -n editor -P -F #{window_id} #{pane_id} #{window_index}",
+		"move-window -s @1 -t project:1",
+		"set-environment -t project SWM_STORY_NAME STORY-123",
+		"set-environment -t project SWM_STORY_BRANCH_NAME STORY-123",
+		"set-option -g @swm-story STORY-123",
+		"set-option -g @swm-story-branch STORY-123",
+		"set-option -t =project: " + projectOption + " github.com/owner/project",
+		"split-window -d -t @1 -c /code/project/web -P -F #{pane_id}",
+		"select-layout -t @1 c3a1,80x24,0,0{40x24,0,0,0,39x24,41,0,1}",
+		"send-keys -t %1 vim main.go Enter",
+		"select-pane -t %2",
+		"new-window -d -t project:2 -c /code/project -n shell -P -F #{window_id} #{pane_id} #{window_index}",
+		"select-layout -t @3 b25d,80x24,0,0,2",
+		"select-pane -t %3",
+		"select-window -t @3",
+	}, f.Commands)
+
+	t.Run("the restore commands", func(t *testing.T) {
+		tmx := &Manager{restoreCommands: []string{"make"}}
+		assert.True(t, tmx.shouldRestoreCommand("make deploy"))
+		assert.False(t, tmx.shouldRestoreCommand("vim main.go"))
+		assert.False(t, tmx.shouldRestoreCommand(""))
+
+		tmx = &Manager{}
+		assert.True(t, tmx.shouldRestoreCommand("/usr/bin/vim main.go"))
+		assert.False(t, tmx.shouldRestoreCommand("rm -rf build"))
+	})
+}
+
+func TestSaveSnapshot(t *testing.T) {
+	// create a temporary directory
+	dir, err := ioutil.TempDir("", "swm-test-*")
+	require.NoError(t, err)
+
+	// delete it once we are done here
+	defer func() { os.RemoveAll(dir) }()
+
+	// keep the snapshots within the temporary directory
+	xdg.DataHome = dir
+	defer xdg.Reload()
+
+	st, err := story.New("feature/STORY-123", "")
+	require.NoError(t, err)
+	tmx := &Manager{story: st}
+
+	_, err = tmx.LoadSnapshot()
+	assert.True(t, errors.Is(err, ErrNoSnapshot))
+
+	s := &Snapshot{
+		CreatedAt: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		Sessions: []*SessionSnapshot{{
+			Name:    "project",
+			Windows: []*WindowSnapshot{{Name: "zsh", Layout: "b25d,80x24,0,0,2", Panes: []*PaneSnapshot{{Path: "/code/project", Command: "vim"}}}},
+		}},
+	}
+	require.NoError(t, tmx.saveSnapshot(s))
+	assert.FileExists(t, dir+"/swm/tmux/swm-feature_STORY-123/snapshot.json")
+
+	loaded, err := tmx.LoadSnapshot()
+	require.NoError(t, err)
+	assert.Equal(t, s, loaded)
+
+	t.Run("the restore prompt", func(t *testing.T) {
+		var prompted *Snapshot
+		f := &FakeClient{}
+		tmx := &Manager{story: st, client: f, restorePrompt: func(s *Snapshot) (bool, error) {
+			prompted = s
+			return false, nil
+		}}
+		require.NoError(t, tmx.offerRestore())
+		assert.Equal(t, s, prompted)
+		// nothing is restored once declined
+		assert.Equal(t, []string{"list-sessions -F #{session_name}"}, f.Commands)
+	})
+
+	t.Run("the data of a removed story", func(t *testing.T) {
+		require.NoError(t, RemoveData(st.GetName()))
+		assert.NoDirExists(t, dir+"/swm/tmux/swm-feature_STORY-123")
+
+		_, err := tmx.LoadSnapshot()
+		assert.True(t, errors.Is(err, ErrNoSnapshot))
+	})
+}
@@ -0,0 +1,110 @@
+package tmux
+
+import (
+	"os"
+	"path"
+)
+
+// configFileName is the name of the tmux config file of a story, within the
+// directory of the story, or of all the stories, at the root of the code.
+const configFileName = ".tmux.conf"
+
+// The user options of the tmux servers of the stories, for the status line.
+const (
+	storyOption       = "@swm-story"
+	storyBranchOption = "@swm-story-branch"
+)
+
+// WithSocketDir sets the directory of the sockets of the tmux servers, they
+// are given to tmux with -S. The sockets are named with tmux -L, in the
+// socket directory of tmux, by default.
+func WithSocketDir(dir string) Option {
+	return func(m *Manager) { m.socketDir = dir }
+}
+
+// WithConfigFile sets the tmux config file of the servers of the stories, it
+// is used unless the story or the code has its own config file, see
+// configFile. The servers read the default config file of tmux otherwise.
+func WithConfigFile(path string) Option {
+	return func(m *Manager) { m.configFile = path }
+}
+
+// socketFlags returns the flags of tmux selecting the server of the socket.
+func (t *Manager) socketFlags(socketName string) []string {
+	if t.socketDir != "" {
+		return []string{"-S", path.Join(t.socketDir, socketName)}
+	}
+
+	return []string{"-L", socketName}
+}
+
+// socketPath returns the path of the socket of the server of the story.
+func (t *Manager) socketPath() string {
+	dir := t.socketDir
+	if dir == "" {
+		dir = socketDir()
+	}
+
+	return path.Join(dir, t.socketName())
+}
+
+// serverFlags returns the flags of tmux selecting the server of the story,
+// along with its config file which is read when the server starts.
+func (t *Manager) serverFlags() []string {
+	flags := t.socketFlags(t.socketName())
+	if f := t.storyConfigFile(); f != "" {
+		flags = append(flags, "-f", f)
+	}
+
+	return flags
+}
+
+// storyConfigFile returns the tmux config file of the server of the story,
+// the first existing of the .tmux.conf file of the directory of the story
+// and of the root of the code, or the config file of WithConfigFile. It's
+// empty for the default config file of tmux.
+func (t *Manager) storyConfigFile() string {
+	var candidates []string
+	if t.code != nil {
+		if t.story != nil {
+			candidates = append(candidates, path.Join(t.code.StoriesDir(), t.story.GetName(), configFileName))
+		}
+		candidates = append(candidates, path.Join(t.code.Path(), configFileName))
+	}
+	for _, f := range candidates {
+		if fileExists(f) {
+			return f
+		}
+	}
+
+	return t.configFile
+}
+
+// setStatusOptions sets the user options of the server naming the story and
+// its branch, #{@swm-story} and #{@swm-story-branch} in the status line.
+func (t *Manager) setStatusOptions() error {
+	if t.story == nil {
+		return nil
+	}
+
+	for _, kv := range [][]string{
+		{storyOption, t.story.GetName()},
+		{storyBranchOption, t.story.GetBranchName()},
+	} {
+		if _, err := t.client.Run("set-option", "-g", kv[0], kv[1]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ensureSocketDir creates the directory of the sockets, tmux does not create
+// it.
+func (t *Manager) ensureSocketDir() error {
+	if t.socketDir == "" {
+		return nil
+	}
+
+	return os.MkdirAll(t.socketDir, 0700)
+}
@@ -0,0 +1,80 @@
+package tmux
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"testing"
+
+	"github.com/adrg/xdg"
+	"github.com/kalbasit/swm/code"
+	"github.com/kalbasit/swm/story"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerFlags(t *testing.T) {
+	// create a temporary directory
+	dir, err := ioutil.TempDir("", "swm-test-*")
+	require.NoError(t, err)
+
+	// delete it once we are done here
+	defer func() { os.RemoveAll(dir) }()
+
+	// keep the index of the code within the temporary directory
+	xdg.CacheHome = dir
+	defer xdg.Reload()
+
+	c := code.New(dir, regexp.MustCompile("^.snapshots$"))
+	st, err := story.New("STORY-123", "")
+	require.NoError(t, err)
+
+	t.Run("the socket directory of tmux", func(t *testing.T) {
+		tmx := &Manager{code: c, story: st}
+		assert.Equal(t, []string{"-L", "swm-STORY-123"}, tmx.serverFlags())
+		assert.Equal(t, path.Join(socketDir(), "swm-STORY-123"), tmx.socketPath())
+	})
+
+	t.Run("a socket directory", func(t *testing.T) {
+		tmx := &Manager{code: c, story: st, socketDir: path.Join(dir, "sockets")}
+		require.NoError(t, tmx.ensureSocketDir())
+		assert.DirExists(t, path.Join(dir, "sockets"))
+		assert.Equal(t, []string{"-S", path.Join(dir, "sockets", "swm-STORY-123")}, tmx.serverFlags())
+		assert.Equal(t, path.Join(dir, "sockets", "swm-STORY-123"), tmx.socketPath())
+	})
+
+	t.Run("the config files", func(t *testing.T) {
+		tmx := &Manager{code: c, story: st, configFile: "/etc/swm/tmux.conf"}
+		assert.Equal(t, []string{"-L", "swm-STORY-123", "-f", "/etc/swm/tmux.conf"}, tmx.serverFlags())
+
+		codeConfig := path.Join(dir, configFileName)
+		require.NoError(t, ioutil.WriteFile(codeConfig, nil, 0644))
+		assert.Equal(t, codeConfig, tmx.storyConfigFile())
+
+		storyConfig := path.Join(c.StoriesDir(), "STORY-123", configFileName)
+		require.NoError(t, os.MkdirAll(path.Dir(storyConfig), 0755))
+		require.NoError(t, ioutil.WriteFile(storyConfig, nil, 0644))
+		assert.Equal(t, storyConfig, tmx.storyConfigFile())
+
+		// the config of the story is not used outside of the story
+		tmx = &Manager{code: c}
+		assert.Equal(t, []string{"-L", "swm", "-f", codeConfig}, tmx.serverFlags())
+	})
+}
+
+func TestSetStatusOptions(t *testing.T) {
+	f := &FakeClient{}
+	tmx := &Manager{client: f}
+	require.NoError(t, tmx.setStatusOptions())
+	assert.Empty(t, f.Commands)
+
+	st, err := story.New("STORY-123", "feature/story-123")
+	require.NoError(t, err)
+	tmx.story = st
+	require.NoError(t, tmx.setStatusOptions())
+	assert.Equal(t, []string{
+		"set-option -g " + storyOption + " STORY-123",
+		"set-option -g " + storyBranchOption + " feature/story-123",
+	}, f.Commands)
+}
@@ -0,0 +1,47 @@
+package tmux
+
+import (
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/kalbasit/swm/ifaces"
+)
+
+// StorySeparator separates the story from the project in the entries of the
+// combined picker.
+const StorySeparator = " › "
+
+// StoryEntries returns the entries of the combined picker, formatted as
+// story › project, for the projects having a worktree in the stories (in
+// the order of the stories) followed by the import paths of all the
+// projects.
+func StoryEntries(c ifaces.Code, stories []ifaces.Story) []string {
+	projects := c.Projects()
+	sort.Slice(projects, func(i, j int) bool { return projects[i].String() < projects[j].String() })
+
+	var entries []string
+	for _, s := range stories {
+		for _, prj := range projects {
+			if fi, err := os.Stat(prj.Path(s)); err == nil && fi.IsDir() {
+				entries = append(entries, s.GetName()+StorySeparator+prj.String())
+			}
+		}
+	}
+	for _, prj := range projects {
+		entries = append(entries, prj.String())
+	}
+
+	return entries
+}
+
+// ParseStoryEntry returns the name of the story, empty for the projects
+// outside of any story, and the import path of the project of an entry of
+// the combined picker.
+func ParseStoryEntry(entry string) (string, string) {
+	if i := strings.Index(entry, StorySeparator); i >= 0 {
+		return entry[:i], entry[i+len(StorySeparator):]
+	}
+
+	return "", entry
+}
@@ -0,0 +1,70 @@
+package tmux
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"testing"
+
+	"github.com/adrg/xdg"
+	"github.com/kalbasit/swm/code"
+	"github.com/kalbasit/swm/ifaces"
+	"github.com/kalbasit/swm/story"
+	"github.com/kalbasit/swm/testhelper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoryEntries(t *testing.T) {
+	// create a temporary directory
+	dir, err := ioutil.TempDir("", "swm-test-*")
+	require.NoError(t, err)
+
+	// delete it once we are done here
+	defer func() { os.RemoveAll(dir) }()
+
+	// keep the index of the code within the temporary directory
+	xdg.CacheHome = dir
+	defer xdg.Reload()
+
+	// create the filesystem we want to scan
+	require.NoError(t, testhelper.CreateProjects(dir))
+
+	// create a code
+	c := code.New(dir, regexp.MustCompile("^.snapshots$"))
+	require.NoError(t, c.Scan())
+
+	s1, err := story.New("STORY-1", "")
+	require.NoError(t, err)
+	s2, err := story.New("feature/STORY-2", "")
+	require.NoError(t, err)
+
+	// the worktrees of the stories
+	for _, p := range []string{
+		"STORY-1/github.com/owner2/repo2",
+		"STORY-1/github.com/owner1/repo1",
+		"feature/STORY-2/github.com/owner3/repo3",
+	} {
+		require.NoError(t, os.MkdirAll(path.Join(c.StoriesDir(), p), 0755))
+	}
+
+	assert.Equal(t, []string{
+		"feature/STORY-2 › github.com/owner3/repo3",
+		"STORY-1 › github.com/owner1/repo1",
+		"STORY-1 › github.com/owner2/repo2",
+		"github.com/owner1/repo1",
+		"github.com/owner2/repo2",
+		"github.com/owner3/repo3",
+	}, StoryEntries(c, []ifaces.Story{s2, s1}))
+}
+
+func TestParseStoryEntry(t *testing.T) {
+	sn, ip := ParseStoryEntry("feature/STORY-2 › github.com/owner3/repo3")
+	assert.Equal(t, "feature/STORY-2", sn)
+	assert.Equal(t, "github.com/owner3/repo3", ip)
+
+	sn, ip = ParseStoryEntry("github.com/owner3/repo3")
+	assert.Empty(t, sn)
+	assert.Equal(t, "github.com/owner3/repo3", ip)
+}
@@ -0,0 +1,106 @@
+package tmux
+
+import (
+	"strings"
+
+	"github.com/kalbasit/swm/ifaces"
+	"github.com/pkg/errors"
+)
+
+// The strategies mapping the projects to the tmux sessions.
+const (
+	// SessionPerProject runs a session per project on the server of the
+	// story.
+	SessionPerProject = "session"
+
+	// WindowPerProject runs a window per project in the session of the story.
+	WindowPerProject = "window"
+)
+
+// ErrUnknownSessionStrategy is returned by New if the session strategy is not
+// one of SessionPerProject or WindowPerProject.
+var ErrUnknownSessionStrategy = errors.New("unknown session strategy")
+
+// projectOption is the user option of the windows naming the import path of
+// their project.
+const projectOption = "@swm-project"
+
+// SessionStrategies returns the names of the session strategies.
+func SessionStrategies() []string {
+	return []string{SessionPerProject, WindowPerProject}
+}
+
+// WithSessionStrategy sets how the projects are mapped to the tmux sessions,
+// it's SessionPerProject by default. In WindowPerProject, the windows of the
+// layout of a project are flattened into a single window.
+func WithSessionStrategy(strategy string) Option {
+	return func(m *Manager) { m.strategy = strategy }
+}
+
+// validateStrategy returns an error wrapping ErrUnknownSessionStrategy if the
+// strategy is unknown, an empty strategy is SessionPerProject.
+func validateStrategy(strategy string) error {
+	switch strategy {
+	case "", SessionPerProject, WindowPerProject:
+		return nil
+	default:
+		return errors.Wrapf(ErrUnknownSessionStrategy, "%q is not one of %s", strategy, strings.Join(SessionStrategies(), ", "))
+	}
+}
+
+// windowPerProject returns true if the projects are windows of the session of
+// the story.
+func (t *Manager) windowPerProject() bool {
+	return t.strategy == WindowPerProject
+}
+
+// storySessionName returns the name of the session of the story, holding the
+// windows of the projects in WindowPerProject.
+func (t *Manager) storySessionName() string {
+	if t.story != nil {
+		return sanitizeSessionName(t.story.GetName())
+	}
+
+	return "swm"
+}
+
+// windowTarget returns the target of the window of the session of the story
+// named windowName, the names are matched exactly.
+func (t *Manager) windowTarget(windowName string) string {
+	return "=" + t.storySessionName() + ":=" + windowName
+}
+
+// ensureWindow creates the window of the project in the session of the story,
+// along with the session, unless it's already running. It returns the name
+// of the window, the windows are found and named like the sessions in
+// EnsureSession.
+func (t *Manager) ensureWindow(project ifaces.Project) (string, error) {
+	windowName, running := claimName(t.SessionName(project), project.String(), t.runningProjects())
+	if running {
+		return windowName, nil
+	}
+
+	m, err := t.projectManifest(project)
+	if err != nil {
+		return "", err
+	}
+	l, err := t.layoutFor(project, m)
+	if err != nil {
+		return "", err
+	}
+	l = t.reopenVimSession(l, project.String()).Flatten()
+	l.Windows[0].Name = windowName
+
+	sessionName := t.storySessionName()
+	_, err = t.client.Run("has-session", "-t="+sessionName)
+	// the environment of the project is only exported into its window
+	windowIDs, err := t.newWindows(sessionName, err != nil, project.Path(t.story), l, m.Env, nil)
+	if err != nil {
+		return "", errors.Wrap(err, "error creating the tmux window")
+	}
+	if _, err := t.client.Run("set-option", "-w", "-t", windowIDs[0], projectOption, project.String()); err != nil {
+		return "", err
+	}
+
+	return windowName, nil
+}
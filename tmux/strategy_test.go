@@ -0,0 +1,99 @@
+package tmux
+
+import (
+	"io/ioutil"
+	"os"
+	"regexp"
+	"testing"
+
+	"github.com/adrg/xdg"
+	"github.com/kalbasit/swm/code"
+	"github.com/kalbasit/swm/story"
+	"github.com/kalbasit/swm/testhelper"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateStrategy(t *testing.T) {
+	for _, strategy := range []string{"", SessionPerProject, WindowPerProject} {
+		assert.NoError(t, validateStrategy(strategy))
+	}
+	assert.True(t, errors.Is(validateStrategy("pane"), ErrUnknownSessionStrategy))
+}
+
+func TestEnsureWindow(t *testing.T) {
+	// create a temporary directory
+	dir, err := ioutil.TempDir("", "swm-test-*")
+	require.NoError(t, err)
+
+	// delete it once we are done here
+	defer func() { os.RemoveAll(dir) }()
+
+	// keep the index and the vim sessions within the temporary directory
+	xdg.CacheHome = dir
+	xdg.DataHome = dir
+	defer xdg.Reload()
+
+	// create the filesystem we want to scan
+	require.NoError(t, testhelper.CreateProjects(dir))
+
+	// create a code
+	c := code.New(dir, regexp.MustCompile("^.snapshots$"))
+	require.NoError(t, c.Scan())
+	prj, err := c.GetProjectByRelativePath("github.com/owner1/repo1")
+	require.NoError(t, err)
+
+	st, err := story.New("STORY-123", "")
+	require.NoError(t, err)
+
+	windowName := sanitizeSessionName("github.com/owner1/repo1")
+
+	t.Run("the session of the story is not running", func(t *testing.T) {
+		f := &FakeClient{Errors: map[string]error{
+			"list-windows": errors.New("can't find session"),
+			"has-session":  errors.New("can't find session"),
+		}}
+		tmx := &Manager{code: c, story: st, client: f, strategy: WindowPerProject}
+
+		name, err := tmx.EnsureSession(prj)
+		require.NoError(t, err)
+		assert.Equal(t, windowName, name)
+		assert.Contains(t, f.Commands, "new-session -d -s STORY-123 -c "+prj.Path(st)+" -P -F #{window_id} #{pane_id} -n "+windowName)
+		assert.Contains(t, f.Commands, "set-environment -t STORY-123 SWM_STORY_NAME STORY-123")
+		assert.Contains(t, f.Commands, "split-window -d -t %1 -c "+prj.Path(st)+" -P -F #{pane_id} -v")
+		assert.Equal(t, "set-option -w -t @1 "+projectOption+" github.com/owner1/repo1", f.Commands[len(f.Commands)-1])
+		// the layout was flattened into one window
+		for _, command := range f.Commands {
+			assert.NotContains(t, command, "new-window")
+		}
+	})
+
+	t.Run("the session of the story is running", func(t *testing.T) {
+		f := &FakeClient{Outputs: map[string]string{"list-windows": "github" + dotChar + "com/owner2/repo2"}}
+		tmx := &Manager{code: c, story: st, client: f, strategy: WindowPerProject}
+
+		name, err := tmx.EnsureSession(prj)
+		require.NoError(t, err)
+		assert.Equal(t, windowName, name)
+		assert.Contains(t, f.Commands, "new-window -d -t STORY-123: -c "+prj.Path(st)+" -P -F #{window_id} #{pane_id} -n "+windowName)
+		for _, command := range f.Commands {
+			assert.NotContains(t, command, "set-environment")
+		}
+
+		sessions, err := tmx.Sessions()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"github" + dotChar + "com/owner2/repo2"}, sessions)
+	})
+
+	t.Run("the window of the project is running", func(t *testing.T) {
+		f := &FakeClient{Outputs: map[string]string{"list-windows": windowName}}
+		tmx := &Manager{code: c, story: st, client: f, strategy: WindowPerProject}
+
+		name, err := tmx.EnsureSession(prj)
+		require.NoError(t, err)
+		assert.Equal(t, windowName, name)
+		assert.Equal(t, []string{"list-windows -t =STORY-123 -F #{window_name}\t#{" + projectOption + "}"}, f.Commands)
+		assert.Equal(t, "=STORY-123:="+windowName, tmx.windowTarget(name))
+	})
+}
@@ -0,0 +1,123 @@
+package tmux
+
+import (
+	"os"
+	"path"
+	"strings"
+
+	"github.com/kalbasit/swm/layout"
+)
+
+// vimSessionPath returns the path of the vim session file of the project
+// (or the tmux session) identified by name.
+func (t *Manager) vimSessionPath(name string) string {
+	return path.Join(t.dataDir(), "vim", name+".vim")
+}
+
+// VimExit asks every vim running on the server to exit, with the exit keys
+// of the protected process named vim. By default the session of each vim is
+// saved, with :mksession, and reopened the next time the project is started.
+// It returns an error wrapping ErrProtectedProcessRefusedToExit and listing
+// the panes still running vim once the timeout expires.
+func (t *Manager) VimExit() error {
+	panes, err := t.getBusyPanes(t.vimProcesses())
+	if err != nil {
+		return err
+	}
+
+	return t.exitProcesses(panes)
+}
+
+// vimProcesses returns the protected processes named vim, or the default one
+// if none is configured.
+func (t *Manager) vimProcesses() []ProtectedProcess {
+	var res []ProtectedProcess
+	for _, p := range t.protectedProcesses {
+		if p.Name == vimProcessName {
+			res = append(res, p)
+		}
+	}
+	if len(res) > 0 {
+		return res
+	}
+
+	for _, p := range DefaultProtectedProcesses() {
+		if p.Name == vimProcessName {
+			// the default patterns always compile
+			_ = p.compile()
+			res = append(res, p)
+		}
+	}
+
+	return res
+}
+
+// vimEscape escapes the file name for the command line of vim, like
+// fnameescape() does.
+func vimEscape(p string) string {
+	var b strings.Builder
+	for _, r := range p {
+		if strings.ContainsRune(" \t\n*?[{`$\\%#'\"|!<", r) {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// withVimSession returns the command opening the vim session file if the
+// command (or the last command of a list separated by semicolons) starts vim
+// without arguments.
+func withVimSession(command, sessionPath string) (string, bool) {
+	i := strings.LastIndex(command, ";") + 1
+	fields := strings.Fields(command[i:])
+	if len(fields) != 1 {
+		return command, false
+	}
+	switch path.Base(fields[0]) {
+	case "vi", "vim", "nvim":
+	default:
+		return command, false
+	}
+
+	return strings.TrimRight(command, " ") + " -S " + shellQuote(sessionPath), true
+}
+
+// reopenVimSession returns the layout with the first command starting vim
+// opening the vim session saved for the project, if any.
+func (t *Manager) reopenVimSession(l layout.Layout, importPath string) layout.Layout {
+	sessionPath := t.vimSessionPath(importPath)
+	if !fileExists(sessionPath) {
+		return l
+	}
+
+	// copy the windows and the panes to leave the layouts of the config as is
+	res := layout.Layout{Windows: make([]layout.Window, len(l.Windows))}
+	copy(res.Windows, l.Windows)
+	for i := range res.Windows {
+		w := &res.Windows[i]
+		var ok bool
+		if w.Command, ok = withVimSession(w.Command, sessionPath); ok {
+			return res
+		}
+		w.Panes = append([]layout.Pane(nil), w.Panes...)
+		for j := range w.Panes {
+			if w.Panes[j].Command, ok = withVimSession(w.Panes[j].Command, sessionPath); ok {
+				return res
+			}
+		}
+	}
+
+	return res
+}
+
+func fileExists(p string) bool {
+	fi, err := os.Stat(p)
+	return err == nil && fi.Mode().IsRegular()
+}
+
+// shellQuote quotes the argument for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
@@ -0,0 +1,82 @@
+package tmux
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/adrg/xdg"
+	"github.com/kalbasit/swm/layout"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVimEscape(t *testing.T) {
+	assert.Equal(t, `/data/swm/vim/github.com/owner1/repo1.vim`, vimEscape("/data/swm/vim/github.com/owner1/repo1.vim"))
+	assert.Equal(t, `/Library/Application\ Support/swm/\%\#.vim`, vimEscape("/Library/Application Support/swm/%#.vim"))
+}
+
+func TestWithVimSession(t *testing.T) {
+	for command, want := range map[string]string{
+		"vim":  "vim -S '/data/a b.vim'",
+		"nvim": "nvim -S '/data/a b.vim'",
+		"type vim_ready &>/dev/null && vim_ready; clear; vim": "type vim_ready &>/dev/null && vim_ready; clear; vim -S '/data/a b.vim'",
+		"/usr/bin/vim ": "/usr/bin/vim -S '/data/a b.vim'",
+	} {
+		t.Run(command, func(t *testing.T) {
+			got, ok := withVimSession(command, "/data/a b.vim")
+			assert.True(t, ok)
+			assert.Equal(t, want, got)
+		})
+	}
+
+	for _, command := range []string{"", "vim main.go", "make watch", "vim; make watch"} {
+		t.Run(command, func(t *testing.T) {
+			got, ok := withVimSession(command, "/data/a b.vim")
+			assert.False(t, ok)
+			assert.Equal(t, command, got)
+		})
+	}
+}
+
+func TestReopenVimSession(t *testing.T) {
+	// create a temporary directory
+	dir, err := ioutil.TempDir("", "swm-test-*")
+	require.NoError(t, err)
+
+	// delete it once we are done here
+	defer func() { os.RemoveAll(dir) }()
+
+	// keep the vim sessions within the temporary directory
+	xdg.DataHome = dir
+	defer xdg.Reload()
+
+	tmx := &Manager{}
+	l := layout.Layout{Windows: []layout.Window{
+		{Command: "make watch", Panes: []layout.Pane{{Command: "nvim"}}},
+		{Command: "vim"},
+	}}
+
+	t.Run("without a session", func(t *testing.T) {
+		assert.Equal(t, l, tmx.reopenVimSession(l, "github.com/owner1/repo1"))
+	})
+
+	sessionPath := tmx.vimSessionPath("github.com/owner1/repo1")
+	assert.Equal(t, path.Join(dir, "swm", "tmux", "swm", "vim", "github.com", "owner1", "repo1.vim"), sessionPath)
+	require.NoError(t, os.MkdirAll(path.Dir(sessionPath), 0755))
+	require.NoError(t, ioutil.WriteFile(sessionPath, []byte("\" session"), 0644))
+
+	t.Run("with a session", func(t *testing.T) {
+		assert.Equal(t, layout.Layout{Windows: []layout.Window{
+			{Command: "make watch", Panes: []layout.Pane{{Command: "nvim -S " + shellQuote(sessionPath)}}},
+			{Command: "vim"},
+		}}, tmx.reopenVimSession(l, "github.com/owner1/repo1"))
+		// the layout is left as is
+		assert.Equal(t, "nvim", l.Windows[0].Panes[0].Command)
+	})
+}
+
+func TestUnsanitizeSessionName(t *testing.T) {
+	assert.Equal(t, "github.com:8080/owner1/repo1", unsanitizeSessionName(sanitizeSessionName("github.com:8080/owner1/repo1")))
+}
@@ -0,0 +1,133 @@
+package tools
+
+import (
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// The external tools used by swm.
+const (
+	Git  = "git"
+	Hg   = "hg"
+	Jj   = "jj"
+	Tmux = "tmux"
+	Fzf  = "fzf"
+	Ps   = "ps"
+
+	Zellij = "zellij"
+
+	Sk    = "sk"
+	Rofi  = "rofi"
+	Dmenu = "dmenu"
+)
+
+var (
+	// ErrNotFound is returned by Path if the executable of the tool cannot be
+	// found
+	ErrNotFound = errors.New("executable not found")
+
+	// ErrUnknownTool is returned if the tool is not one of the known tools
+	ErrUnknownTool = errors.New("unknown tool")
+
+	// versionArgs are the arguments printing the version of each tool
+	versionArgs = map[string][]string{
+		Git:  {"--version"},
+		Hg:   {"--version", "--quiet"},
+		Jj:   {"--version"},
+		Tmux: {"-V"},
+		Fzf:  {"--version"},
+		Ps:   {"--version"},
+
+		Zellij: {"--version"},
+
+		Sk:    {"--version"},
+		Rofi:  {"-version"},
+		Dmenu: {"-v"},
+	}
+
+	// overrides holds the paths configured for the tools
+	mu        sync.Mutex
+	overrides = make(map[string]string)
+)
+
+// Names returns the sorted names of the known tools.
+func Names() []string {
+	names := make([]string, 0, len(versionArgs))
+	for name := range versionArgs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// SetPath overrides the executable of the tool, p is either a path or a name
+// looked up in the PATH. An empty p restores the default.
+func SetPath(name, p string) error {
+	if _, ok := versionArgs[name]; !ok {
+		return errors.Wrapf(ErrUnknownTool, "%q is not one of %s", name, strings.Join(Names(), ", "))
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if p == "" {
+		delete(overrides, name)
+	} else {
+		overrides[name] = p
+	}
+
+	return nil
+}
+
+// Configured returns the executable configured for the tool, it's the name
+// of the tool unless it was overridden with SetPath.
+func Configured(name string) string {
+	mu.Lock()
+	defer mu.Unlock()
+	if p, ok := overrides[name]; ok {
+		return p
+	}
+
+	return name
+}
+
+// Path returns the path of the executable of the tool. The tool is looked up
+// every time so it's only required by the commands that actually use it.
+func Path(name string) (string, error) {
+	p := Configured(name)
+	res, err := exec.LookPath(p)
+	if err != nil {
+		return "", errors.Wrapf(ErrNotFound, "error looking up the %s executable %q, is it installed? %s", name, p, err)
+	}
+
+	return res, nil
+}
+
+// Command returns the command running the tool with the arguments.
+func Command(name string, args ...string) (*exec.Cmd, error) {
+	p, err := Path(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return exec.Command(p, args...), nil
+}
+
+// Version returns the first line printed by the tool when asked for its
+// version.
+func Version(name string) (string, error) {
+	cmd, err := Command(name, versionArgs[name]...)
+	if err != nil {
+		return "", err
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return "", errors.Wrapf(err, "error getting the version of %s", name)
+	}
+
+	return strings.TrimSpace(strings.SplitN(string(out), "\n", 2)[0]), nil
+}
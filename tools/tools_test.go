@@ -0,0 +1,44 @@
+package tools
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNames(t *testing.T) {
+	assert.Equal(t, []string{"dmenu", "fzf", "git", "hg", "jj", "ps", "rofi", "sk", "tmux", "zellij"}, Names())
+}
+
+func TestPath(t *testing.T) {
+	defer SetPath(Fzf, "")
+
+	t.Run("an override is used", func(t *testing.T) {
+		sh, err := os.Executable()
+		require.NoError(t, err)
+		require.NoError(t, SetPath(Fzf, sh))
+
+		p, err := Path(Fzf)
+		if assert.NoError(t, err) {
+			assert.Equal(t, sh, p)
+		}
+	})
+
+	t.Run("a missing executable is reported", func(t *testing.T) {
+		require.NoError(t, SetPath(Fzf, path.Join(os.TempDir(), "swm-does-not-exist")))
+
+		_, err := Path(Fzf)
+		assert.True(t, errors.Is(err, ErrNotFound))
+
+		_, err = Version(Fzf)
+		assert.True(t, errors.Is(err, ErrNotFound))
+	})
+
+	t.Run("unknown tools are refused", func(t *testing.T) {
+		assert.True(t, errors.Is(SetPath("emacs", "/usr/bin/emacs"), ErrUnknownTool))
+	})
+}
@@ -0,0 +1,81 @@
+package vcs
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+
+	"github.com/pkg/errors"
+)
+
+var gitWorktreeRootRegex = regexp.MustCompile(`^gitdir: (.*)/\.git/worktrees/.*$`)
+
+// git implements the VCS interface for Git, workspaces are Git worktrees.
+type git struct{}
+
+func (*git) Name() string { return "git" }
+
+func (*git) Detect(p string) bool {
+	_, err := os.Stat(path.Join(p, ".git"))
+	return err == nil
+}
+
+func (*git) RepositoryRoot(p string) (string, error) {
+	dotGit := path.Join(p, ".git")
+	gitInfo, err := os.Stat(dotGit)
+	if err != nil {
+		return "", errors.Wrap(err, "error stat the .git directory")
+	}
+	if gitInfo.IsDir() {
+		return path.Clean(p), nil
+	}
+
+	gitC, err := ioutil.ReadFile(dotGit)
+	if err != nil {
+		return "", errors.Wrap(err, "error reading the .git file")
+	}
+
+	sm := gitWorktreeRootRegex.FindSubmatch(bytes.Trim(gitC, "\n"))
+	if len(sm) != 2 {
+		return "", ErrDotGitMalformed
+	}
+
+	return path.Clean(string(sm[1])), nil
+}
+
+func (*git) Clone(url, p string) error {
+	return runAttached("", "git", "clone", url, p)
+}
+
+func (*git) CreateWorkspace(repositoryPath, workspacePath, branch string) error {
+	return run(repositoryPath, "git", "worktree", "add", "-B", branch, workspacePath)
+}
+
+func (*git) RemoveWorkspace(repositoryPath, workspacePath string) error {
+	if _, err := os.Stat(workspacePath); os.IsNotExist(err) {
+		// the worktree is already gone, only prune its administrative files
+		return run(repositoryPath, "git", "worktree", "prune")
+	}
+
+	return run(repositoryPath, "git", "worktree", "remove", "--force", workspacePath)
+}
+
+func (*git) Branches(repositoryPath string) ([]string, error) {
+	out, err := output(repositoryPath, "git", "for-each-ref", "--format=%(refname:short)", "refs/heads/")
+	if err != nil {
+		return nil, err
+	}
+
+	return lines(out), nil
+}
+
+func (*git) Status(p string) ([]string, error) {
+	out, err := output(p, "git", "status", "--porcelain")
+	if err != nil {
+		return nil, err
+	}
+
+	return lines(out), nil
+}
@@ -0,0 +1,42 @@
+package vcs
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/kalbasit/swm/testhelper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitWorkspace(t *testing.T) {
+	// create a temporary directory
+	dir, err := ioutil.TempDir("", "swm-test-*")
+	require.NoError(t, err)
+
+	// delete it once we are done here
+	defer func() { os.RemoveAll(dir) }()
+
+	// create the filesystem we want to work with
+	require.NoError(t, testhelper.CreateProjects(dir))
+
+	g := &git{}
+	rp := path.Join(dir, "repositories", "github.com/owner1/repo1")
+	wp := path.Join(dir, "stories", t.Name(), "github.com/owner1/repo1")
+
+	require.NoError(t, g.CreateWorkspace(rp, wp, t.Name()))
+	assert.FileExists(t, path.Join(wp, ".git"))
+	assert.FileExists(t, path.Join(wp, "some-file"))
+	assert.True(t, g.Detect(wp))
+
+	root, err := g.RepositoryRoot(wp)
+	if assert.NoError(t, err) {
+		assert.Equal(t, rp, root)
+	}
+
+	require.NoError(t, g.RemoveWorkspace(rp, wp))
+	assert.NoDirExists(t, wp)
+	assert.NoDirExists(t, path.Join(rp, ".git", "worktrees", path.Base(wp)))
+}
@@ -0,0 +1,73 @@
+package vcs
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/pkg/errors"
+)
+
+// hg implements the VCS interface for Mercurial, workspaces are created with
+// the share extension and the story branch is a bookmark.
+type hg struct{}
+
+func (*hg) Name() string { return "hg" }
+
+func (*hg) Detect(p string) bool { return isDir(path.Join(p, ".hg")) }
+
+func (*hg) RepositoryRoot(p string) (string, error) {
+	if !isDir(path.Join(p, ".hg")) {
+		return "", ErrNotRepository
+	}
+
+	// a share records the path to the .hg directory of its source
+	sp, err := ioutil.ReadFile(path.Join(p, ".hg", "sharedpath"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return path.Clean(p), nil
+		}
+		return "", errors.Wrap(err, "error reading the .hg/sharedpath file")
+	}
+
+	return path.Dir(path.Clean(string(bytes.TrimSpace(sp)))), nil
+}
+
+func (*hg) Clone(url, p string) error {
+	return runAttached("", "hg", "clone", url, p)
+}
+
+func (*hg) CreateWorkspace(repositoryPath, workspacePath, branch string) error {
+	if err := os.MkdirAll(path.Dir(workspacePath), 0755); err != nil {
+		return errors.Wrap(err, "error creating the parent directory of the workspace")
+	}
+	if err := run(repositoryPath, "hg", "--config", "extensions.share=", "share", repositoryPath, workspacePath); err != nil {
+		return err
+	}
+
+	return run(workspacePath, "hg", "bookmark", "--force", branch)
+}
+
+func (*hg) RemoveWorkspace(repositoryPath, workspacePath string) error {
+	// shares are not tracked by their source, removing the files is enough
+	return os.RemoveAll(workspacePath)
+}
+
+func (*hg) Branches(repositoryPath string) ([]string, error) {
+	out, err := output(repositoryPath, "hg", "bookmarks", "--template", "{bookmark}\n")
+	if err != nil {
+		return nil, err
+	}
+
+	return lines(out), nil
+}
+
+func (*hg) Status(p string) ([]string, error) {
+	out, err := output(p, "hg", "status")
+	if err != nil {
+		return nil, err
+	}
+
+	return lines(out), nil
+}
@@ -0,0 +1,106 @@
+package vcs
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// jj implements the VCS interface for Jujutsu, workspaces are Jujutsu
+// workspaces named after the story branch.
+type jj struct{}
+
+func (*jj) Name() string { return "jj" }
+
+func (*jj) Detect(p string) bool { return isDir(path.Join(p, ".jj")) }
+
+func (*jj) RepositoryRoot(p string) (string, error) {
+	dotJJ := path.Join(p, ".jj")
+	if !isDir(dotJJ) {
+		return "", ErrNotRepository
+	}
+
+	// the repo is a directory in the main workspace, and a file holding the
+	// path to the repo of the main workspace in the others.
+	repoInfo, err := os.Stat(path.Join(dotJJ, "repo"))
+	if err != nil {
+		return "", errors.Wrap(err, "error stat the .jj/repo")
+	}
+	if repoInfo.IsDir() {
+		return path.Clean(p), nil
+	}
+
+	rp, err := ioutil.ReadFile(path.Join(dotJJ, "repo"))
+	if err != nil {
+		return "", errors.Wrap(err, "error reading the .jj/repo file")
+	}
+	repoPath := string(bytes.TrimSpace(rp))
+	if !path.IsAbs(repoPath) {
+		repoPath = path.Join(dotJJ, repoPath)
+	}
+
+	// the repo lives at <root>/.jj/repo
+	return path.Dir(path.Dir(path.Clean(repoPath))), nil
+}
+
+func (*jj) Clone(url, p string) error {
+	return runAttached("", "jj", "git", "clone", "--colocate", url, p)
+}
+
+func (*jj) CreateWorkspace(repositoryPath, workspacePath, branch string) error {
+	if err := os.MkdirAll(path.Dir(workspacePath), 0755); err != nil {
+		return errors.Wrap(err, "error creating the parent directory of the workspace")
+	}
+
+	if err := run(repositoryPath, "jj", "workspace", "add", "--name", workspaceName(branch), workspacePath); err != nil {
+		return err
+	}
+
+	// the bookmark of the story points to the working copy of the workspace
+	if err := run(workspacePath, "jj", "bookmark", "set", branch, "-r", "@"); err != nil {
+		run(workspacePath, "jj", "workspace", "forget")
+		os.RemoveAll(workspacePath)
+		return err
+	}
+
+	return nil
+}
+
+func (*jj) RemoveWorkspace(repositoryPath, workspacePath string) error {
+	if isDir(workspacePath) {
+		// forget the workspace the command is run from
+		if err := run(workspacePath, "jj", "workspace", "forget"); err != nil {
+			return err
+		}
+	}
+
+	return os.RemoveAll(workspacePath)
+}
+
+func (*jj) Branches(repositoryPath string) ([]string, error) {
+	out, err := output(repositoryPath, "jj", "bookmark", "list", "--template", `name ++ "\n"`)
+	if err != nil {
+		return nil, err
+	}
+
+	return lines(out), nil
+}
+
+func (*jj) Status(p string) ([]string, error) {
+	// the working copy is a commit, its changes are the status
+	out, err := output(p, "jj", "diff", "--summary")
+	if err != nil {
+		return nil, err
+	}
+
+	return lines(out), nil
+}
+
+// workspaceName returns the name of the workspace for the branch.
+func workspaceName(branch string) string {
+	return strings.Replace(branch, "/", "_", -1)
+}
@@ -0,0 +1,51 @@
+package vcs
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/kalbasit/swm/testhelper"
+	"github.com/kalbasit/swm/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJJWorkspace(t *testing.T) {
+	if _, err := tools.Path(tools.Jj); err != nil {
+		t.Skip("jj is not installed")
+	}
+
+	// create a temporary directory
+	dir, err := ioutil.TempDir("", "swm-test-*")
+	require.NoError(t, err)
+
+	// delete it once we are done here
+	defer func() { os.RemoveAll(dir) }()
+
+	// create the filesystem we want to work with, colocated with jj
+	require.NoError(t, testhelper.CreateProjects(dir))
+	rp := path.Join(dir, "repositories", "github.com/owner1/repo1")
+	require.NoError(t, run(rp, "jj", "git", "init", "--colocate"))
+
+	j := &jj{}
+	wp := path.Join(dir, "stories", t.Name(), "github.com/owner1/repo1")
+
+	require.NoError(t, j.CreateWorkspace(rp, wp, "feature/"+t.Name()))
+	assert.FileExists(t, path.Join(wp, "some-file"))
+	assert.True(t, j.Detect(wp))
+
+	root, err := j.RepositoryRoot(wp)
+	if assert.NoError(t, err) {
+		assert.Equal(t, rp, root)
+	}
+
+	// the bookmark of the story is created
+	branches, err := j.Branches(rp)
+	require.NoError(t, err)
+	assert.Contains(t, branches, "feature/"+t.Name())
+
+	require.NoError(t, j.RemoveWorkspace(rp, wp))
+	assert.NoDirExists(t, wp)
+}
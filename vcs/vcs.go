@@ -0,0 +1,154 @@
+package vcs
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/kalbasit/swm/ifaces"
+	"github.com/kalbasit/swm/tools"
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrUnknownVCS is returned by Get if no VCS is registered under the given
+	// name
+	ErrUnknownVCS = errors.New("unknown version control system")
+
+	// ErrNotRepository is returned if the path is not a repository of any of
+	// the supported version control systems
+	ErrNotRepository = errors.New("not a repository")
+
+	// ErrDotGitMalformed is returned if .git is malformed
+	ErrDotGitMalformed = errors.New(".git is malformed")
+
+	// DefaultName is the name of the VCS used when none is specified
+	DefaultName = "git"
+
+	// backends is the list of the supported backends, ordered by their
+	// detection priority. Jujutsu comes first as its repositories are usually
+	// colocated with a Git repository.
+	backends = []ifaces.VCS{
+		&jj{},
+		&hg{},
+		&git{},
+	}
+)
+
+// Get returns the VCS registered under name, the default VCS is returned if
+// name is empty.
+func Get(name string) (ifaces.VCS, error) {
+	if name == "" {
+		name = DefaultName
+	}
+	for _, b := range backends {
+		if b.Name() == name {
+			return b, nil
+		}
+	}
+
+	return nil, errors.Wrapf(ErrUnknownVCS, "%q is not one of %s", name, strings.Join(Names(), ", "))
+}
+
+// Names returns the sorted names of all the supported VCS.
+func Names() []string {
+	names := make([]string, 0, len(backends))
+	for _, b := range backends {
+		names = append(names, b.Name())
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Detect returns the VCS managing the repository (or the workspace) at p. It
+// returns ErrNotRepository if p is not managed by any of the supported VCS.
+func Detect(p string) (ifaces.VCS, error) {
+	for _, b := range backends {
+		if b.Detect(p) {
+			return b, nil
+		}
+	}
+
+	return nil, ErrNotRepository
+}
+
+// IsRepository returns true if p is a repository (or a workspace) of any of
+// the supported VCS.
+func IsRepository(p string) bool {
+	_, err := Detect(p)
+	return err == nil
+}
+
+// isDir returns true if p exists and is a directory.
+func isDir(p string) bool {
+	fi, err := os.Stat(p)
+	return err == nil && fi.IsDir()
+}
+
+// run runs the program in dir and returns an error holding the combined
+// output of the program if it fails.
+func run(dir, program string, args ...string) error {
+	programPath, err := tools.Path(program)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(programPath, args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("error running %s %s: %s\nOutput:\n%s", program, strings.Join(args, " "), err, string(out))
+	}
+
+	return nil
+}
+
+// output runs the program in dir and returns its standard output.
+func output(dir, program string, args ...string) (string, error) {
+	programPath, err := tools.Path(program)
+	if err != nil {
+		return "", err
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(programPath, args...)
+	cmd.Dir = dir
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("error running %s %s: %s\nOutput:\n%s", program, strings.Join(args, " "), err, stderr.String())
+	}
+
+	return string(out), nil
+}
+
+// lines returns the non-empty lines of the output.
+func lines(out string) []string {
+	var res []string
+	for _, line := range strings.Split(out, "\n") {
+		if strings.TrimSpace(line) != "" {
+			res = append(res, line)
+		}
+	}
+
+	return res
+}
+
+// runAttached runs the program attached to the standard output and error.
+func runAttached(dir, program string, args ...string) error {
+	programPath, err := tools.Path(program)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(programPath, args...)
+	cmd.Dir = dir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	return cmd.Run()
+}
@@ -0,0 +1,129 @@
+package vcs
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGet(t *testing.T) {
+	v, err := Get("")
+	require.NoError(t, err)
+	assert.Equal(t, "git", v.Name())
+
+	for _, name := range []string{"git", "hg", "jj"} {
+		v, err := Get(name)
+		if assert.NoError(t, err) {
+			assert.Equal(t, name, v.Name())
+		}
+	}
+
+	_, err = Get("svn")
+	assert.True(t, errors.Is(err, ErrUnknownVCS))
+}
+
+func TestDetect(t *testing.T) {
+	// create a temporary directory
+	dir, err := ioutil.TempDir("", "swm-test-*")
+	require.NoError(t, err)
+
+	// delete it once we are done here
+	defer func() { os.RemoveAll(dir) }()
+
+	tests := map[string][]string{
+		"git": {".git"},
+		"hg":  {".hg"},
+		"jj":  {".jj", ".git"},
+	}
+
+	for name, dirs := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := path.Join(dir, name)
+			for _, d := range dirs {
+				require.NoError(t, os.MkdirAll(path.Join(p, d), 0755))
+			}
+
+			v, err := Detect(p)
+			if assert.NoError(t, err) {
+				assert.Equal(t, name, v.Name())
+			}
+			assert.True(t, IsRepository(p))
+		})
+	}
+
+	_, err = Detect(dir)
+	assert.True(t, errors.Is(err, ErrNotRepository))
+	assert.False(t, IsRepository(dir))
+}
+
+func TestRepositoryRoot(t *testing.T) {
+	// create a temporary directory
+	dir, err := ioutil.TempDir("", "swm-test-*")
+	require.NoError(t, err)
+
+	// delete it once we are done here
+	defer func() { os.RemoveAll(dir) }()
+
+	repo := path.Join(dir, "repositories", "repo")
+	workspace := path.Join(dir, "stories", "STORY-123", "repo")
+
+	writeFile := func(p, content string) {
+		require.NoError(t, os.MkdirAll(path.Dir(p), 0755))
+		require.NoError(t, ioutil.WriteFile(p, []byte(content), 0644))
+	}
+
+	t.Run("git", func(t *testing.T) {
+		require.NoError(t, os.MkdirAll(path.Join(repo, ".git"), 0755))
+		writeFile(path.Join(workspace, ".git"), "gitdir: "+repo+"/.git/worktrees/repo\n")
+		defer func() { os.RemoveAll(dir) }()
+
+		for _, p := range []string{repo, workspace} {
+			root, err := (&git{}).RepositoryRoot(p)
+			if assert.NoError(t, err) {
+				assert.Equal(t, repo, root)
+			}
+		}
+
+		writeFile(path.Join(workspace, ".git"), "malformed")
+		_, err := (&git{}).RepositoryRoot(workspace)
+		assert.True(t, errors.Is(err, ErrDotGitMalformed))
+	})
+
+	t.Run("hg", func(t *testing.T) {
+		require.NoError(t, os.MkdirAll(path.Join(repo, ".hg"), 0755))
+		writeFile(path.Join(workspace, ".hg", "sharedpath"), repo+"/.hg")
+		defer func() { os.RemoveAll(dir) }()
+
+		for _, p := range []string{repo, workspace} {
+			root, err := (&hg{}).RepositoryRoot(p)
+			if assert.NoError(t, err) {
+				assert.Equal(t, repo, root)
+			}
+		}
+	})
+
+	t.Run("jj", func(t *testing.T) {
+		require.NoError(t, os.MkdirAll(path.Join(repo, ".jj", "repo"), 0755))
+		writeFile(path.Join(workspace, ".jj", "repo"), repo+"/.jj/repo")
+		defer func() { os.RemoveAll(dir) }()
+
+		for _, p := range []string{repo, workspace} {
+			root, err := (&jj{}).RepositoryRoot(p)
+			if assert.NoError(t, err) {
+				assert.Equal(t, repo, root)
+			}
+		}
+
+		// relative paths are relative to the .jj directory
+		writeFile(path.Join(workspace, ".jj", "repo"), "../../../../repositories/repo/.jj/repo")
+		root, err := (&jj{}).RepositoryRoot(workspace)
+		if assert.NoError(t, err) {
+			assert.Equal(t, repo, root)
+		}
+	})
+}